@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// APIAuth guards the exporter's write endpoints with a bearer token. These
+// endpoints change exporter state, so they are disabled until a token is
+// configured, independent of any basic authentication in the web config.
+type APIAuth struct {
+	token []byte
+}
+
+// NewAPIAuth reads the bearer token from tokenFile. An empty tokenFile
+// disables the write endpoints.
+func NewAPIAuth(tokenFile string) (*APIAuth, error) {
+	if len(tokenFile) == 0 {
+		return &APIAuth{}, nil
+	}
+
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	token = bytes.TrimSpace(token)
+	if len(token) == 0 {
+		return nil, errors.New("API token file is empty")
+	}
+
+	return &APIAuth{token: token}, nil
+}
+
+// Wrap returns a handler that only calls next for requests carrying the
+// configured bearer token.
+func (a *APIAuth) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(a.token) == 0 {
+			http.Error(w, "API is disabled, no token configured", http.StatusForbidden)
+			return
+		}
+
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, "Bearer ")), a.token) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
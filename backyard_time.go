@@ -0,0 +1,48 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// Layout of the Backyard datetime attribute, the controller local time.
+	controllerTimeLayout = "2006-01-02T15:04:05.999"
+)
+
+var (
+	backyardControllerTime = prometheus.NewDesc(prometheus.BuildFQName(namespace, "backyard", "controller_time_seconds"),
+		"Controller clock as seconds since the epoch, interpreting its local time in the configured timezone.", []string{"msp_system_id"}, nil)
+	backyardClockSkew = prometheus.NewDesc(prometheus.BuildFQName(namespace, "backyard", "controller_clock_skew_seconds"),
+		"Controller clock minus the exporter wall clock, in seconds.", []string{"msp_system_id"}, nil)
+	backyardConfigUpdated = prometheus.NewDesc(prometheus.BuildFQName(namespace, "backyard", "config_updated_timestamp_seconds"),
+		"When the controller configuration was last updated, as seconds since the epoch.", []string{"msp_system_id"}, nil)
+)
+
+// parseControllerTime parses the Backyard datetime attribute, which carries
+// no zone information, in the given location.
+func parseControllerTime(value string, location *time.Location) (time.Time, error) {
+	return time.ParseInLocation(controllerTimeLayout, value, location)
+}
+
+// buildBackyardTimeMetrics exports the controller clock and configuration
+// timestamps of the Backyard telemetry element.
+func (e *Exporter) buildBackyardTimeMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	for _, item := range telemetryDataResponse.itemsNamed("backyard") {
+		if value, ok := item.attributes["datetime"]; ok {
+			controllerTime, err := parseControllerTime(value, e.controllerLocation)
+			if err == nil {
+				ch <- prometheus.MustNewConstMetric(backyardControllerTime, prometheus.GaugeValue, float64(controllerTime.UnixNano())/1e9, mspSystemId)
+				ch <- prometheus.MustNewConstMetric(backyardClockSkew, prometheus.GaugeValue, controllerTime.Sub(e.now()).Seconds(), mspSystemId)
+			}
+		}
+
+		if value, ok := item.attributes["config_updated_time"]; ok {
+			configUpdated, err := time.Parse(time.RFC3339Nano, value)
+			if err == nil {
+				ch <- prometheus.MustNewConstMetric(backyardConfigUpdated, prometheus.GaugeValue, float64(configUpdated.UnixNano())/1e9, mspSystemId)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestBackyardTimeMetrics(t *testing.T) {
+	telemetryData, err := parseTelemetryDataResponse(string(readFixture(t, "get_telemetry_data_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.controllerLocation = time.FixedZone("EDT", -4*60*60)
+	exporter.now = func() time.Time { return time.Date(2022, 4, 5, 3, 3, 30, 0, time.UTC) }
+
+	ch := make(chan prometheus.Metric, 10)
+	exporter.buildBackyardTimeMetrics(ch, "54321", *telemetryData)
+	values := collectValues(t, ch)
+
+	expected := map[string]float64{
+		"omnilogic_backyard_controller_time_seconds":          1649127815.299,
+		"omnilogic_backyard_controller_clock_skew_seconds":    5.299,
+		"omnilogic_backyard_config_updated_timestamp_seconds": 1649088419.254,
+	}
+
+	for name, want := range expected {
+		if got := valueOf(t, values, name, "msp_system_id=54321"); math.Abs(got-want) > 0.001 {
+			t.Fatalf("Expected %v to be %v but found %v", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// defaultTotalDissolvedSolids is assumed when no TDS measurement is
+	// supplied. It only shifts the LSI by a few hundredths.
+	defaultTotalDissolvedSolids = 1000
+
+	// balancedLSI is the LSI range around zero that is considered balanced.
+	balancedLSI = 0.3
+)
+
+var (
+	chemistryPH = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chemistry", "ph"),
+		"pH used for the saturation index, from the CSAD probe or a manual reading.", []string{"msp_system_id", "system_id", "name", "source"}, nil)
+	chemistryLSI = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chemistry", "lsi"),
+		"Langelier Saturation Index of the body of water.", []string{"msp_system_id", "system_id", "name"}, nil)
+	chemistryBalance = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chemistry", "balance"),
+		"Water balance classification derived from the LSI, 1 for the current state.", []string{"msp_system_id", "system_id", "name", "state"}, nil)
+
+	chemistryBalanceStates = []string{"corrosive", "balanced", "scaling"}
+)
+
+// manualChemistry returns a manually measured chemistry parameter of a body of
+// water, such as total_alkalinity, calcium_hardness or cyanuric_acid. Readings
+// submitted to the readings API take precedence over the configuration file.
+func (e *Exporter) manualChemistry(mspSystemId string, bodyOfWaterSystemId string, parameter string) (float64, bool) {
+	if reading, ok := e.manualReadings.Get(mspSystemId, bodyOfWaterSystemId, parameter); ok {
+		return reading.Value, true
+	}
+	return e.config.chemistry(mspSystemId, bodyOfWaterSystemId, parameter)
+}
+
+// langelierSaturationIndex computes the LSI from the pH, the water
+// temperature in Celsius and concentrations in ppm. Cyanurate alkalinity is
+// subtracted from the total alkalinity using the common CYA/3 approximation.
+// It returns false when the inputs do not allow a meaningful index.
+func langelierSaturationIndex(ph float64, celsius float64, totalAlkalinity float64, calciumHardness float64, cyanuricAcid float64, totalDissolvedSolids float64) (float64, bool) {
+	carbonateAlkalinity := totalAlkalinity - cyanuricAcid/3
+	if carbonateAlkalinity <= 0 || calciumHardness <= 0 || totalDissolvedSolids <= 0 {
+		return 0, false
+	}
+
+	a := (math.Log10(totalDissolvedSolids) - 1) / 10
+	b := -13.12*math.Log10(celsius+273.15) + 34.55
+	c := math.Log10(calciumHardness) - 0.4
+	d := math.Log10(carbonateAlkalinity)
+
+	saturationPH := (9.3 + a + b) - (c + d)
+	return ph - saturationPH, true
+}
+
+// chemistryBalanceState classifies an LSI as corrosive, balanced or scaling.
+func chemistryBalanceState(lsi float64) string {
+	switch {
+	case lsi < -balancedLSI:
+		return "corrosive"
+	case lsi > balancedLSI:
+		return "scaling"
+	}
+	return "balanced"
+}
+
+// buildChemistryMetrics exports the Langelier Saturation Index of each body of
+// water, combining the CSAD pH and the water temperature with manually
+// supplied alkalinity, calcium hardness and CYA.
+func (e *Exporter) buildChemistryMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	config, ok := e.mspConfigs[mspSystemId]
+	if !ok {
+		return
+	}
+
+	probePH := map[string]float64{}
+	for _, item := range telemetryDataResponse.itemsNamed("csad") {
+		if value, err := strconv.ParseFloat(item.attributes["ph"], 64); err == nil && value > 0 {
+			probePH[item.systemId] = value
+		}
+	}
+
+	bodiesOfWater := map[string]TelemetryDataItem{}
+	for _, item := range telemetryDataResponse.itemsNamed("body_of_water") {
+		bodiesOfWater[item.systemId] = item
+	}
+
+	for _, bow := range config.Backyard.BodiesOfWater {
+		item, ok := bodiesOfWater[bow.SystemID]
+		if !ok {
+			continue
+		}
+
+		ph, source, havePH := 0.0, "", false
+		for _, csad := range bow.CSADs {
+			if value, ok := probePH[csad.SystemID]; ok {
+				ph, source, havePH = value, "probe", true
+			}
+		}
+		if !havePH {
+			if value, ok := e.manualChemistry(mspSystemId, bow.SystemID, "ph"); ok {
+				ph, source, havePH = value, "manual", true
+			}
+		}
+		if !havePH {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(chemistryPH, prometheus.GaugeValue, ph, mspSystemId, bow.SystemID, bow.Name, source)
+
+		temperature, valid := e.waterTemperature(mspSystemId, telemetryDataResponse, item)
+		if !valid {
+			continue
+		}
+		celsius := temperature
+		if config.waterTemperatureUnit(bow) == "fahrenheit" {
+			celsius = (temperature - 32) * 5 / 9
+		}
+
+		totalAlkalinity, ok := e.manualChemistry(mspSystemId, bow.SystemID, "total_alkalinity")
+		if !ok {
+			continue
+		}
+		calciumHardness, ok := e.manualChemistry(mspSystemId, bow.SystemID, "calcium_hardness")
+		if !ok {
+			continue
+		}
+		cyanuricAcid, _ := e.manualChemistry(mspSystemId, bow.SystemID, "cyanuric_acid")
+		totalDissolvedSolids, ok := e.manualChemistry(mspSystemId, bow.SystemID, "total_dissolved_solids")
+		if !ok {
+			totalDissolvedSolids = defaultTotalDissolvedSolids
+		}
+
+		lsi, ok := langelierSaturationIndex(ph, celsius, totalAlkalinity, calciumHardness, cyanuricAcid, totalDissolvedSolids)
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(chemistryLSI, prometheus.GaugeValue, lsi, mspSystemId, bow.SystemID, bow.Name)
+
+		balance := chemistryBalanceState(lsi)
+		for _, state := range chemistryBalanceStates {
+			value := 0.0
+			if state == balance {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(chemistryBalance, prometheus.GaugeValue, value, mspSystemId, bow.SystemID, bow.Name, state)
+		}
+	}
+}
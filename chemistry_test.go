@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLangelierSaturationIndex(t *testing.T) {
+	// 29C, 70 ppm carbonate alkalinity and 250 ppm calcium hardness give a
+	// saturation pH of about 7.67.
+	lsi, ok := langelierSaturationIndex(7.5, 28.89, 80, 250, 30, 1000)
+	if !ok || math.Abs(lsi-(-0.17)) > 0.01 {
+		t.Fatalf("Expected an LSI of -0.17 but found %v", lsi)
+	}
+
+	if _, ok := langelierSaturationIndex(7.5, 28.89, 10, 250, 30, 1000); ok {
+		t.Fatal("Expected no LSI when CYA exceeds the total alkalinity.")
+	}
+
+	for lsi, state := range map[float64]string{-0.5: "corrosive", -0.3: "balanced", 0.2: "balanced", 0.31: "scaling"} {
+		if found := chemistryBalanceState(lsi); found != state {
+			t.Fatalf("Expected %v for an LSI of %v but found %v", state, lsi, found)
+		}
+	}
+}
+
+func TestChemistryMetrics(t *testing.T) {
+	mspConfig, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	config, err := LoadConfig(path.Join("test", "config.yml"))
+
+	if err != nil {
+		t.Fatal("Error loading configuration file.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.mspConfigs["54321"] = mspConfig
+	exporter.config = config
+
+	poll := func(ph string, filterState int) map[string]float64 {
+		telemetryData, err := parseTelemetryDataResponse(fmt.Sprintf(`<STATUS version="1.0">
+    <Backyard systemId="54321" statusVersion="8" airTemp="70" />
+    <BodyOfWater systemId="1" waterTemp="84" flow="1" />
+    <Filter systemId="2" filterSpeed="71" filterState="%d" />
+    <CSAD systemId="0" ph="%v" orp="" status="0" mode="0" />
+</STATUS>`, filterState, ph))
+		if err != nil {
+			t.Fatal("Error parsing telemetry data response.", err)
+		}
+		ch := make(chan prometheus.Metric, 10)
+		exporter.buildChemistryMetrics(ch, "54321", *telemetryData)
+		return collectValues(t, ch)
+	}
+
+	// Without a probe the manual pH of the configuration is used.
+	values := poll("", 1)
+	if valueOf(t, values, "omnilogic_chemistry_ph", "system_id=1", "source=manual") != 7.5 {
+		t.Fatal("Expected the manual pH of 7.5.")
+	}
+	if lsi := valueOf(t, values, "omnilogic_chemistry_lsi", "system_id=1", "name=Pool"); math.Abs(lsi-(-0.17)) > 0.01 {
+		t.Fatalf("Expected an LSI of -0.17 but found %v", lsi)
+	}
+	if valueOf(t, values, "omnilogic_chemistry_balance", "system_id=1", "state=balanced") != 1 ||
+		valueOf(t, values, "omnilogic_chemistry_balance", "system_id=1", "state=scaling") != 0 {
+		t.Fatal("Expected balanced water.")
+	}
+
+	// A CSAD probe in the body of water takes precedence.
+	mspConfig.Backyard.BodiesOfWater[0].CSADs = []MspCSAD{{MspEquipment: MspEquipment{SystemID: "0"}}}
+	values = poll("8.2", 1)
+	if valueOf(t, values, "omnilogic_chemistry_ph", "system_id=1", "source=probe") != 8.2 {
+		t.Fatal("Expected the probe pH of 8.2.")
+	}
+	if valueOf(t, values, "omnilogic_chemistry_balance", "system_id=1", "state=scaling") != 1 {
+		t.Fatal("Expected scaling water at pH 8.2.")
+	}
+
+	// The water temperature is stale while the filter is off.
+	values = poll("8.2", 0)
+	if len(values) != 1 {
+		t.Fatal("Expected only the pH without a valid water temperature.", values)
+	}
+}
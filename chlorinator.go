@@ -0,0 +1,113 @@
+package main
+
+import (
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	chlorinatorConfigInfo = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chlorinator", "config_info"),
+		"Chlorinator settings from the MSP configuration.",
+		[]string{"msp_system_id", "system_id", "name", "mode", "cell_type", "salt_monitor", "dispenser_type"}, nil)
+	chlorinatorEnabled = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chlorinator", "enabled"),
+		"Whether the chlorinator is enabled in the MSP configuration.", []string{"msp_system_id", "system_id", "name"}, nil)
+	chlorinatorConfiguredTimedPercent = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chlorinator", "configured_timed_percent"),
+		"Percentage of the time the chlorinator generates in timed mode, from the MSP configuration.", []string{"msp_system_id", "system_id", "name"}, nil)
+	chlorinatorSuperChlorTimeout = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chlorinator", "superchlorination_timeout_seconds"),
+		"How long a super-chlorination cycle runs.", []string{"msp_system_id", "system_id", "name"}, nil)
+	chlorinatorSuperChlorinating = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chlorinator", "superchlorinating"),
+		"Whether a super-chlorination (shock) cycle is running.", []string{"msp_system_id", "system_id", "name"}, nil)
+	chlorinatorSuperChlorStarted = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chlorinator", "superchlorination_started_timestamp_seconds"),
+		"When the running super-chlorination cycle was first seen, as seconds since the epoch.", []string{"msp_system_id", "system_id", "name"}, nil)
+	chlorinatorSuperChlorRemaining = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chlorinator", "superchlorination_remaining_seconds"),
+		"Estimated time until the running super-chlorination cycle times out.", []string{"msp_system_id", "system_id", "name"}, nil)
+)
+
+// superChlorinationState remembers when scMode of a chlorinator turned on.
+// The start is unknown for a cycle that was already running when first
+// polled, so no remaining time is estimated for it.
+type superChlorinationState struct {
+	active  bool
+	started time.Time
+}
+
+// chlorinators returns all chlorinators of the site by System-Id.
+func (c *MspConfig) chlorinators() map[string]MspChlorinator {
+	chlorinators := map[string]MspChlorinator{}
+	for _, bow := range c.Backyard.BodiesOfWater {
+		for _, chlorinator := range bow.Chlorinators {
+			chlorinators[chlorinator.SystemID] = chlorinator
+		}
+	}
+	return chlorinators
+}
+
+// buildChlorinatorConfigMetrics exports the chlorinator settings of a site's
+// MSP configuration.
+func (e *Exporter) buildChlorinatorConfigMetrics(ch chan<- prometheus.Metric, mspSystemId string, config *MspConfig) {
+	for _, chlorinator := range config.chlorinators() {
+		ch <- prometheus.MustNewConstMetric(chlorinatorConfigInfo, prometheus.GaugeValue, 1, mspSystemId, chlorinator.SystemID, chlorinator.Name,
+			chlorinator.Mode, chlorinator.CellType, chlorinator.SaltMonitor, chlorinator.DispenserType)
+		ch <- prometheus.MustNewConstMetric(chlorinatorEnabled, prometheus.GaugeValue, yesNo(chlorinator.Enabled), mspSystemId, chlorinator.SystemID, chlorinator.Name)
+		ch <- prometheus.MustNewConstMetric(chlorinatorConfiguredTimedPercent, prometheus.GaugeValue, chlorinator.TimedPercent, mspSystemId, chlorinator.SystemID, chlorinator.Name)
+		ch <- prometheus.MustNewConstMetric(chlorinatorSuperChlorTimeout, prometheus.GaugeValue, chlorinator.SuperChlorTimeout*3600, mspSystemId, chlorinator.SystemID, chlorinator.Name)
+	}
+}
+
+// buildSuperChlorinationMetrics tracks super-chlorination cycles from the
+// scMode telemetry and estimates their remaining time from the configured
+// SuperChlor-Timeout.
+func (e *Exporter) buildSuperChlorinationMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	chlorinators := map[string]MspChlorinator{}
+	if config, ok := e.mspConfigs[mspSystemId]; ok {
+		chlorinators = config.chlorinators()
+	}
+	now := e.now()
+
+	for _, item := range telemetryDataResponse.itemsNamed("chlorinator") {
+		scMode, ok := item.attributes["sc_mode"]
+		if !ok {
+			continue
+		}
+		active := scMode != "0"
+		chlorinator := chlorinators[item.systemId]
+
+		key := mspSystemId + "/" + item.systemId
+		state, seen := e.superChlorination[key]
+		if !seen {
+			state = &superChlorinationState{active: active}
+			e.superChlorination[key] = state
+		} else if active != state.active {
+			state.active = active
+			state.started = time.Time{}
+			if active {
+				state.started = now
+				level.Info(e.logger).Log("msg", "Super-chlorination started.", "MspSystemID", mspSystemId, "SystemID", item.systemId)
+			} else {
+				level.Info(e.logger).Log("msg", "Super-chlorination ended.", "MspSystemID", mspSystemId, "SystemID", item.systemId)
+			}
+		}
+
+		value := 0.0
+		if active {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(chlorinatorSuperChlorinating, prometheus.GaugeValue, value, mspSystemId, item.systemId, chlorinator.Name)
+
+		if !active || state.started.IsZero() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(chlorinatorSuperChlorStarted, prometheus.GaugeValue, float64(state.started.Unix()), mspSystemId, item.systemId, chlorinator.Name)
+
+		if chlorinator.SuperChlorTimeout > 0 {
+			timeout := time.Duration(chlorinator.SuperChlorTimeout * float64(time.Hour))
+			remaining := timeout - now.Sub(state.started)
+			if remaining < 0 {
+				remaining = 0
+			}
+			ch <- prometheus.MustNewConstMetric(chlorinatorSuperChlorRemaining, prometheus.GaugeValue, remaining.Seconds(), mspSystemId, item.systemId, chlorinator.Name)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestChlorinatorConfigMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	exporter.buildChlorinatorConfigMetrics(ch, "54321", config)
+	values := collectValues(t, ch)
+
+	if valueOf(t, values, "omnilogic_chlorinator_config_info", "system_id=3", "name=Chlorinator", "mode=CHLOR_OP_MODE_TIMED",
+		"cell_type=CELL_TYPE_T15", "salt_monitor=LOW_SALT", "dispenser_type=SALT_DISPENSING") != 1 {
+		t.Fatal("Expected the chlorinator config info.")
+	}
+	if valueOf(t, values, "omnilogic_chlorinator_enabled", "system_id=3") != 1 {
+		t.Fatal("Expected the chlorinator to be enabled.")
+	}
+	if valueOf(t, values, "omnilogic_chlorinator_configured_timed_percent", "system_id=3") != 30 {
+		t.Fatal("Expected a timed percent of 30.")
+	}
+	if valueOf(t, values, "omnilogic_chlorinator_superchlorination_timeout_seconds", "system_id=3") != 72*3600 {
+		t.Fatal("Expected a super-chlorination timeout of 72 hours.")
+	}
+}
+
+func TestSuperChlorinationMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	now := time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC)
+	exporter.now = func() time.Time { return now }
+	exporter.mspConfigs["54321"] = config
+
+	poll := func(scMode int) map[string]float64 {
+		telemetryData, err := parseTelemetryDataResponse(fmt.Sprintf(`<STATUS version="1.0">
+    <Backyard systemId="54321" statusVersion="8" />
+    <Chlorinator systemId="3" operatingMode="1" Timed-Percent="30" scMode="%d" avgSaltLevel="2785" instantSaltLevel="2618" status="128" />
+</STATUS>`, scMode))
+		if err != nil {
+			t.Fatal("Error parsing telemetry data response.", err)
+		}
+		ch := make(chan prometheus.Metric, 10)
+		exporter.buildSuperChlorinationMetrics(ch, "54321", *telemetryData)
+		return collectValues(t, ch)
+	}
+
+	// A cycle already running at the first poll has an unknown start.
+	values := poll(1)
+	if len(values) != 1 || valueOf(t, values, "omnilogic_chlorinator_superchlorinating", "system_id=3", "name=Chlorinator") != 1 {
+		t.Fatal("Expected only the super-chlorinating flag for a cycle of unknown start.", values)
+	}
+
+	now = now.Add(time.Minute)
+	values = poll(0)
+	if valueOf(t, values, "omnilogic_chlorinator_superchlorinating", "system_id=3") != 0 {
+		t.Fatal("Expected super-chlorination to have ended.")
+	}
+
+	now = now.Add(time.Minute)
+	started := now
+	poll(1)
+
+	now = now.Add(2 * time.Hour)
+	values = poll(1)
+	if valueOf(t, values, "omnilogic_chlorinator_superchlorination_started_timestamp_seconds", "system_id=3") != float64(started.Unix()) {
+		t.Fatal("Expected the start of the super-chlorination cycle.")
+	}
+	if valueOf(t, values, "omnilogic_chlorinator_superchlorination_remaining_seconds", "system_id=3") != 70*3600 {
+		t.Fatal("Expected 70 hours of super-chlorination remaining.")
+	}
+
+	// The estimate does not go negative once the timeout has passed.
+	now = now.Add(80 * time.Hour)
+	values = poll(1)
+	if valueOf(t, values, "omnilogic_chlorinator_superchlorination_remaining_seconds", "system_id=3") != 0 {
+		t.Fatal("Expected no super-chlorination remaining after the timeout.")
+	}
+}
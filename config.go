@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the optional exporter configuration file. It holds settings that
+// are too detailed for command line flags, such as per-pump power curves.
+type Config struct {
+	Pumps       []PumpConfig        `yaml:"pumps"`
+	Tariff      *TariffConfig       `yaml:"tariff"`
+	Maintenance []MaintenanceConfig `yaml:"maintenance"`
+	Chemistry   []ChemistryConfig   `yaml:"chemistry"`
+}
+
+// PumpConfig describes the power draw of a filter pump or pump, identified by
+// the MspSystemID of its site and its systemId.
+type PumpConfig struct {
+	MspSystemID string `yaml:"msp_system_id"`
+	SystemID    string `yaml:"system_id"`
+
+	// Power draw measured at given speeds, interpolated linearly.
+	PowerCurve []PowerPoint `yaml:"power_curve"`
+
+	// Power draw at the rated speed, scaled to other speeds by the pump
+	// affinity laws. RatedRPM defaults to the configured Max-Pump-RPM.
+	RatedWatts float64 `yaml:"rated_watts"`
+	RatedRPM   float64 `yaml:"rated_rpm"`
+
+	// Flow rate measured at given speeds, interpolated linearly.
+	FlowCurve []FlowPoint `yaml:"flow_curve"`
+
+	// Flow rate at the rated speed, scaled linearly to other speeds by the
+	// pump affinity laws.
+	RatedGPM float64 `yaml:"rated_gpm"`
+}
+
+// PowerPoint is the power draw of a pump at a given speed.
+type PowerPoint struct {
+	RPM   float64 `yaml:"rpm"`
+	Watts float64 `yaml:"watts"`
+}
+
+// FlowPoint is the flow rate of a pump in gallons per minute at a given speed.
+type FlowPoint struct {
+	RPM float64 `yaml:"rpm"`
+	GPM float64 `yaml:"gpm"`
+}
+
+// TariffConfig is the price of electricity used to estimate running costs.
+type TariffConfig struct {
+	PricePerKWh float64 `yaml:"price_per_kwh"`
+	Currency    string  `yaml:"currency"`
+}
+
+// MaintenanceConfig is a recurring maintenance task, due once the equipment
+// has run for IntervalHours since it was last serviced. For chlorinators the
+// runtime is the time the cell spent generating.
+type MaintenanceConfig struct {
+	Task          string  `yaml:"task"`
+	MspSystemID   string  `yaml:"msp_system_id"`
+	SystemID      string  `yaml:"system_id"`
+	IntervalHours float64 `yaml:"interval_hours"`
+}
+
+// key identifies the task on its equipment, as the same task may be
+// configured for several sites or pieces of equipment.
+func (t MaintenanceConfig) key() string {
+	return t.Task + "/" + t.MspSystemID + "/" + t.SystemID
+}
+
+// ChemistryConfig holds manually measured water chemistry of a body of water,
+// for values the controller does not measure. Concentrations are in ppm.
+type ChemistryConfig struct {
+	MspSystemID          string   `yaml:"msp_system_id"`
+	BodyOfWaterSystemID  string   `yaml:"body_of_water_system_id"`
+	PH                   *float64 `yaml:"ph"`
+	TotalAlkalinity      *float64 `yaml:"total_alkalinity"`
+	CalciumHardness      *float64 `yaml:"calcium_hardness"`
+	CyanuricAcid         *float64 `yaml:"cyanuric_acid"`
+	TotalDissolvedSolids *float64 `yaml:"total_dissolved_solids"`
+}
+
+// LoadConfig reads and validates the configuration file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %v: %v", path, err)
+	}
+
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	for _, pump := range c.Pumps {
+		if len(pump.MspSystemID) == 0 || len(pump.SystemID) == 0 {
+			return fmt.Errorf("pump requires msp_system_id and system_id")
+		}
+		if !pump.hasPower() && !pump.hasFlow() {
+			return fmt.Errorf("pump %v/%v requires a power_curve, rated_watts, flow_curve or rated_gpm", pump.MspSystemID, pump.SystemID)
+		}
+		for i := 1; i < len(pump.PowerCurve); i++ {
+			if pump.PowerCurve[i].RPM <= pump.PowerCurve[i-1].RPM {
+				return fmt.Errorf("pump %v/%v power_curve must be sorted by increasing rpm", pump.MspSystemID, pump.SystemID)
+			}
+		}
+		for i := 1; i < len(pump.FlowCurve); i++ {
+			if pump.FlowCurve[i].RPM <= pump.FlowCurve[i-1].RPM {
+				return fmt.Errorf("pump %v/%v flow_curve must be sorted by increasing rpm", pump.MspSystemID, pump.SystemID)
+			}
+		}
+	}
+
+	tasks := map[string]bool{}
+	for _, task := range c.Maintenance {
+		if len(task.Task) == 0 || len(task.MspSystemID) == 0 || len(task.SystemID) == 0 {
+			return fmt.Errorf("maintenance requires task, msp_system_id and system_id")
+		}
+		if tasks[task.key()] {
+			return fmt.Errorf("maintenance task %v of %v/%v is defined more than once", task.Task, task.MspSystemID, task.SystemID)
+		}
+		tasks[task.key()] = true
+		if task.IntervalHours <= 0 {
+			return fmt.Errorf("maintenance task %v requires a positive interval_hours", task.Task)
+		}
+	}
+
+	for _, chemistry := range c.Chemistry {
+		if len(chemistry.MspSystemID) == 0 || len(chemistry.BodyOfWaterSystemID) == 0 {
+			return fmt.Errorf("chemistry requires msp_system_id and body_of_water_system_id")
+		}
+	}
+
+	if c.Tariff != nil && c.Tariff.PricePerKWh < 0 {
+		return fmt.Errorf("tariff price_per_kwh must not be negative")
+	}
+
+	return nil
+}
+
+// pump returns the power configuration of a pump, if any.
+func (c *Config) pump(mspSystemId string, systemId string) (PumpConfig, bool) {
+	for _, pump := range c.Pumps {
+		if pump.MspSystemID == mspSystemId && pump.SystemID == systemId {
+			return pump, true
+		}
+	}
+	return PumpConfig{}, false
+}
+
+// chemistry returns the manually measured value of a chemistry parameter.
+func (c *Config) chemistry(mspSystemId string, bodyOfWaterSystemId string, parameter string) (float64, bool) {
+	for _, chemistry := range c.Chemistry {
+		if chemistry.MspSystemID != mspSystemId || chemistry.BodyOfWaterSystemID != bodyOfWaterSystemId {
+			continue
+		}
+
+		var value *float64
+		switch parameter {
+		case "ph":
+			value = chemistry.PH
+		case "total_alkalinity":
+			value = chemistry.TotalAlkalinity
+		case "calcium_hardness":
+			value = chemistry.CalciumHardness
+		case "cyanuric_acid":
+			value = chemistry.CyanuricAcid
+		case "total_dissolved_solids":
+			value = chemistry.TotalDissolvedSolids
+		}
+
+		if value != nil {
+			return *value, true
+		}
+	}
+	return 0, false
+}
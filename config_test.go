@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig(path.Join("test", "config.yml"))
+
+	if err != nil {
+		t.Fatal("Error loading configuration file.", err)
+	}
+
+	pump, ok := config.pump("54321", "2")
+	if !ok || len(pump.PowerCurve) != 3 {
+		t.Fatal("Expected pump 54321/2 with a power curve.", pump)
+	}
+
+	pump, ok = config.pump("98765", "3")
+	if !ok || pump.RatedWatts != 1100 {
+		t.Fatal("Expected pump 98765/3 with rated watts.", pump)
+	}
+
+	if _, ok := config.pump("54321", "3"); ok {
+		t.Fatal("Expected no configuration for pump 54321/3.")
+	}
+
+	if len(config.Maintenance) != 2 || config.Maintenance[0].Task != "filter-clean" || config.Maintenance[0].IntervalHours != 200 {
+		t.Fatal("Expected filter-clean maintenance every 200 hours.", config.Maintenance)
+	}
+
+	if config.Tariff == nil || config.Tariff.PricePerKWh != 0.15 || config.Tariff.Currency != "USD" {
+		t.Fatal("Expected a tariff of 0.15 USD.", config.Tariff)
+	}
+
+	if value, ok := config.chemistry("54321", "1", "calcium_hardness"); !ok || value != 250 {
+		t.Fatal("Expected a calcium hardness of 250 for body of water 54321/1.", value)
+	}
+
+	if _, ok := config.chemistry("54321", "1", "total_dissolved_solids"); ok {
+		t.Fatal("Expected no total dissolved solids for body of water 54321/1.")
+	}
+}
+
+func TestLoadInvalidConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("Error creating temp dir.", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := map[string]string{
+		"unknown field":         "pumps:\n  - msp_system_id: \"1\"\n    system_id: \"2\"\n    rated_watts: 100\n    horsepower: 1\n",
+		"missing ids":           "pumps:\n  - rated_watts: 100\n",
+		"missing chemistry ids": "chemistry:\n  - ph: 7.5\n",
+		"missing power":         "pumps:\n  - msp_system_id: \"1\"\n    system_id: \"2\"\n",
+		"duplicate task": "maintenance:\n  - {task: a, msp_system_id: \"1\", system_id: \"2\", interval_hours: 1}\n" +
+			"  - {task: a, msp_system_id: \"1\", system_id: \"2\", interval_hours: 2}\n",
+		"unsorted curve": "pumps:\n  - msp_system_id: \"1\"\n    system_id: \"2\"\n    power_curve:\n" +
+			"      - {rpm: 2000, watts: 500}\n      - {rpm: 1000, watts: 100}\n",
+	}
+
+	for name, text := range tests {
+		file := path.Join(dir, "config.yml")
+		if err := ioutil.WriteFile(file, []byte(text), 0644); err != nil {
+			t.Fatal("Error writing configuration file.", err)
+		}
+		if _, err := LoadConfig(file); err == nil {
+			t.Fatalf("%v: expected an error loading the configuration file", name)
+		}
+	}
+}
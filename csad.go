@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	csadPH = prometheus.NewDesc(prometheus.BuildFQName(namespace, "csad", "measured_ph"),
+		"pH measured by the CSAD probe.", []string{"msp_system_id", "system_id", "name"}, nil)
+	csadORP = prometheus.NewDesc(prometheus.BuildFQName(namespace, "csad", "orp_millivolts"),
+		"Oxidation reduction potential measured by the CSAD probe.", []string{"msp_system_id", "system_id", "name"}, nil)
+	csadDispensing = prometheus.NewDesc(prometheus.BuildFQName(namespace, "csad", "dispensing"),
+		"Whether the CSAD is dispensing.", []string{"msp_system_id", "system_id", "name"}, nil)
+	csadMode = prometheus.NewDesc(prometheus.BuildFQName(namespace, "csad", "dispensing_mode"),
+		"Dispensing mode of the CSAD, 1 for the current mode.", []string{"msp_system_id", "system_id", "name", "mode"}, nil)
+	csadPHTarget = prometheus.NewDesc(prometheus.BuildFQName(namespace, "csad", "ph_target"),
+		"Configured target pH of the CSAD.", []string{"msp_system_id", "system_id", "name"}, nil)
+	csadPHAlarm = prometheus.NewDesc(prometheus.BuildFQName(namespace, "csad", "ph_alarm"),
+		"Configured pH alarm levels of the CSAD.", []string{"msp_system_id", "system_id", "name", "level"}, nil)
+	csadORPTarget = prometheus.NewDesc(prometheus.BuildFQName(namespace, "csad", "orp_target_millivolts"),
+		"Configured target ORP of the CSAD.", []string{"msp_system_id", "system_id", "name"}, nil)
+	csadORPAlarm = prometheus.NewDesc(prometheus.BuildFQName(namespace, "csad", "orp_alarm_millivolts"),
+		"Configured ORP alarm levels of the CSAD.", []string{"msp_system_id", "system_id", "name", "level"}, nil)
+
+	// csadModes maps the mode telemetry of a CSAD to names, following the
+	// CSADMode enum of python-omnilogic-local (pyomnilogic_local/omnitypes.py).
+	csadModes = []struct {
+		value string
+		name  string
+	}{
+		{"0", "off"},
+		{"1", "automatic"},
+		{"2", "force_on"},
+		{"3", "monitoring"},
+		{"4", "dispensing_off"},
+	}
+)
+
+// buildCSADMetrics exports the pH, ORP and dispensing mode of each CSAD and
+// its configured targets and alarm levels. Controllers without a probe report
+// empty ph and orp attributes, which are skipped.
+func (e *Exporter) buildCSADMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	csads := map[string]MspCSAD{}
+	if config, ok := e.mspConfigs[mspSystemId]; ok {
+		for _, bow := range config.Backyard.BodiesOfWater {
+			for _, csad := range bow.CSADs {
+				csads[csad.SystemID] = csad
+			}
+		}
+	}
+
+	for _, item := range telemetryDataResponse.itemsNamed("csad") {
+		name := csads[item.systemId].Name
+
+		if ph, err := strconv.ParseFloat(item.attributes["ph"], 64); err == nil && ph > 0 {
+			ch <- prometheus.MustNewConstMetric(csadPH, prometheus.GaugeValue, ph, mspSystemId, item.systemId, name)
+		}
+		if orp, err := strconv.ParseFloat(item.attributes["orp"], 64); err == nil && orp > 0 {
+			ch <- prometheus.MustNewConstMetric(csadORP, prometheus.GaugeValue, orp, mspSystemId, item.systemId, name)
+		}
+
+		if status, ok := item.attributes["status"]; ok {
+			dispensing := 0.0
+			if status == "1" {
+				dispensing = 1
+			}
+			ch <- prometheus.MustNewConstMetric(csadDispensing, prometheus.GaugeValue, dispensing, mspSystemId, item.systemId, name)
+		}
+
+		if mode, ok := item.attributes["mode"]; ok {
+			for _, known := range csadModes {
+				value := 0.0
+				if known.value == mode {
+					value = 1
+				}
+				ch <- prometheus.MustNewConstMetric(csadMode, prometheus.GaugeValue, value, mspSystemId, item.systemId, name, known.name)
+			}
+		}
+	}
+
+	for _, csad := range csads {
+		if csad.TargetValue > 0 {
+			ch <- prometheus.MustNewConstMetric(csadPHTarget, prometheus.GaugeValue, csad.TargetValue, mspSystemId, csad.SystemID, csad.Name)
+		}
+		if csad.PHLowAlarmValue > 0 {
+			ch <- prometheus.MustNewConstMetric(csadPHAlarm, prometheus.GaugeValue, csad.PHLowAlarmValue, mspSystemId, csad.SystemID, csad.Name, "low")
+		}
+		if csad.PHHighAlarmValue > 0 {
+			ch <- prometheus.MustNewConstMetric(csadPHAlarm, prometheus.GaugeValue, csad.PHHighAlarmValue, mspSystemId, csad.SystemID, csad.Name, "high")
+		}
+		if csad.ORPTargetLevel > 0 {
+			ch <- prometheus.MustNewConstMetric(csadORPTarget, prometheus.GaugeValue, csad.ORPTargetLevel, mspSystemId, csad.SystemID, csad.Name)
+		}
+		if csad.ORPLowAlarmLevel > 0 {
+			ch <- prometheus.MustNewConstMetric(csadORPAlarm, prometheus.GaugeValue, csad.ORPLowAlarmLevel, mspSystemId, csad.SystemID, csad.Name, "low")
+		}
+		if csad.ORPHighAlarmLevel > 0 {
+			ch <- prometheus.MustNewConstMetric(csadORPAlarm, prometheus.GaugeValue, csad.ORPHighAlarmLevel, mspSystemId, csad.SystemID, csad.Name, "high")
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCSADMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(`<Response>
+    <MSPConfig>
+        <Backyard>
+            <System-Id>0</System-Id>
+            <Body-of-water>
+                <System-Id>1</System-Id>
+                <Name>Pool</Name>
+                <CSAD>
+                    <System-Id>12</System-Id>
+                    <Name>CSAD</Name>
+                    <Enabled>yes</Enabled>
+                    <Target-Value>7.5</Target-Value>
+                    <PH-Low-Alarm-Value>7.0</PH-Low-Alarm-Value>
+                    <PH-High-Alarm-Value>8.0</PH-High-Alarm-Value>
+                    <ORP-Target-Level>700</ORP-Target-Level>
+                    <ORP-Low-Alarm-Level>600</ORP-Low-Alarm-Level>
+                    <ORP-High-Alarm-Level>900</ORP-High-Alarm-Level>
+                </CSAD>
+            </Body-of-water>
+        </Backyard>
+    </MSPConfig>
+</Response>`)
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.mspConfigs["54321"] = config
+
+	telemetryData, err := parseTelemetryDataResponse(`<STATUS version="1.0">
+    <Backyard systemId="54321" statusVersion="8" />
+    <CSAD systemId="12" ph="7.6" orp="720" status="1" mode="1" />
+    <CSAD systemId="0" ph="" orp="" status="0" mode="0" />
+</STATUS>`)
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	exporter.buildCSADMetrics(ch, "54321", *telemetryData)
+	values := collectValues(t, ch)
+
+	if valueOf(t, values, "omnilogic_csad_measured_ph", "system_id=12", "name=CSAD") != 7.6 {
+		t.Fatal("Expected a pH of 7.6.")
+	}
+	if valueOf(t, values, "omnilogic_csad_orp_millivolts", "system_id=12") != 720 {
+		t.Fatal("Expected an ORP of 720 mV.")
+	}
+	if valueOf(t, values, "omnilogic_csad_dispensing", "system_id=12") != 1 {
+		t.Fatal("Expected the CSAD to be dispensing.")
+	}
+	if valueOf(t, values, "omnilogic_csad_dispensing_mode", "system_id=12", "mode=automatic") != 1 ||
+		valueOf(t, values, "omnilogic_csad_dispensing_mode", "system_id=12", "mode=off") != 0 {
+		t.Fatal("Expected the CSAD to be in automatic mode.")
+	}
+	if valueOf(t, values, "omnilogic_csad_ph_target", "system_id=12") != 7.5 ||
+		valueOf(t, values, "omnilogic_csad_ph_alarm", "system_id=12", "level=high") != 8 {
+		t.Fatal("Expected the configured pH target and alarm.")
+	}
+	if valueOf(t, values, "omnilogic_csad_orp_target_millivolts", "system_id=12") != 700 ||
+		valueOf(t, values, "omnilogic_csad_orp_alarm_millivolts", "system_id=12", "level=low") != 600 {
+		t.Fatal("Expected the configured ORP target and alarm.")
+	}
+
+	// Without a probe only the status and mode are exported.
+	if valueOf(t, values, "omnilogic_csad_dispensing_mode", "system_id=0", "mode=off") != 1 {
+		t.Fatal("Expected the unconfigured CSAD to be off.")
+	}
+	for key := range values {
+		if containsAll(key+" ", []string{"measured_ph", " system_id=0 "}) {
+			t.Fatal("Expected no pH without a probe.")
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	freezeConditions = prometheus.NewDesc(prometheus.BuildFQName(namespace, "freeze", "conditions"),
+		"Whether the air temperature is at or below the freeze threshold.", []string{"msp_system_id"}, nil)
+	freezeThreshold = prometheus.NewDesc(prometheus.BuildFQName(namespace, "freeze", "threshold_degrees"),
+		"Freeze threshold in the units of the site's air sensor.", []string{"msp_system_id", "unit"}, nil)
+	freezeFilterRunning = prometheus.NewDesc(prometheus.BuildFQName(namespace, "freeze", "filter_running"),
+		"Whether the filter pump is running.", []string{"msp_system_id", "system_id", "name"}, nil)
+	freezeUnprotected = prometheus.NewDesc(prometheus.BuildFQName(namespace, "freeze", "unprotected"),
+		"1 when freezing conditions are present but the filter pump is not running.", []string{"msp_system_id", "system_id", "name"}, nil)
+	freezeProtectOverrideInterval = prometheus.NewDesc(prometheus.BuildFQName(namespace, "filter", "freeze_protect_override_interval_seconds"),
+		"Configured Freeze-Protect-Override-Interval of the filter.", []string{"msp_system_id", "system_id", "name"}, nil)
+)
+
+// airTemperatureUnit returns "fahrenheit" or "celsius" for the site's air
+// temperature, preferring the units of the air sensor over the system units.
+func (c *MspConfig) airTemperatureUnit() string {
+	return c.temperatureUnit(c.Backyard.Sensors, "SENSOR_AIR_TEMP")
+}
+
+// waterTemperatureUnit returns "fahrenheit" or "celsius" for the water
+// temperature of a body of water.
+func (c *MspConfig) waterTemperatureUnit(bow MspBodyOfWater) string {
+	return c.temperatureUnit(bow.Sensors, "SENSOR_WATER_TEMP")
+}
+
+func (c *MspConfig) temperatureUnit(sensors []MspEquipment, sensorType string) string {
+	for _, sensor := range sensors {
+		if sensor.Type != sensorType {
+			continue
+		}
+		switch sensor.Units {
+		case "UNITS_FAHRENHEIT":
+			return "fahrenheit"
+		case "UNITS_CELSIUS":
+			return "celsius"
+		}
+	}
+
+	if c.System.Units == "Metric" {
+		return "celsius"
+	}
+	return "fahrenheit"
+}
+
+// celsiusIn converts a temperature in Celsius to the given unit.
+func celsiusIn(celsius float64, unit string) float64 {
+	if unit == "fahrenheit" {
+		return celsius*9/5 + 32
+	}
+	return celsius
+}
+
+// buildFreezeMetrics exports whether a site is at risk of freezing and whether
+// its filter pumps are running to protect the plumbing.
+func (e *Exporter) buildFreezeMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	config, ok := e.mspConfigs[mspSystemId]
+	if !ok {
+		return
+	}
+
+	var airTemperature float64
+	var haveAirTemperature bool
+	for _, item := range telemetryDataResponse.itemsNamed("backyard") {
+		if value, err := strconv.ParseFloat(item.attributes["air_temp"], 64); err == nil {
+			airTemperature, haveAirTemperature = value, true
+		}
+	}
+	if !haveAirTemperature {
+		return
+	}
+
+	unit := config.airTemperatureUnit()
+	threshold := celsiusIn(e.freezeThresholdCelsius, unit)
+
+	freezing := airTemperature <= threshold
+	freezingValue := 0.0
+	if freezing {
+		freezingValue = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(freezeConditions, prometheus.GaugeValue, freezingValue, mspSystemId)
+	ch <- prometheus.MustNewConstMetric(freezeThreshold, prometheus.GaugeValue, threshold, mspSystemId, unit)
+
+	filterStates := map[string]string{}
+	for _, item := range telemetryDataResponse.itemsNamed("filter") {
+		filterStates[item.systemId] = item.attributes["filter_state"]
+	}
+
+	for _, bow := range config.Backyard.BodiesOfWater {
+		for _, filter := range bow.Filters {
+			ch <- prometheus.MustNewConstMetric(freezeProtectOverrideInterval, prometheus.GaugeValue, filter.FreezeProtectOverrideInterval, mspSystemId, filter.SystemID, filter.Name)
+
+			state, ok := filterStates[filter.SystemID]
+			if !ok {
+				continue
+			}
+
+			running, unprotected := 0.0, 0.0
+			if state != "0" {
+				running = 1
+			} else if freezing {
+				unprotected = 1
+			}
+
+			ch <- prometheus.MustNewConstMetric(freezeFilterRunning, prometheus.GaugeValue, running, mspSystemId, filter.SystemID, filter.Name)
+			ch <- prometheus.MustNewConstMetric(freezeUnprotected, prometheus.GaugeValue, unprotected, mspSystemId, filter.SystemID, filter.Name)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestFreezeMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	if unit := config.airTemperatureUnit(); unit != "fahrenheit" {
+		t.Fatalf("Expected fahrenheit air sensor but found %v", unit)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.mspConfigs["54321"] = config
+	exporter.freezeThresholdCelsius = 0
+
+	poll := func(airTemp int, filterState int) map[string]float64 {
+		telemetryData, err := parseTelemetryDataResponse(fmt.Sprintf(`<STATUS version="1.0">
+    <Backyard systemId="54321" statusVersion="8" airTemp="%d" />
+    <Filter systemId="2" filterSpeed="71" filterState="%d" />
+</STATUS>`, airTemp, filterState))
+		if err != nil {
+			t.Fatal("Error parsing telemetry data response.", err)
+		}
+		ch := make(chan prometheus.Metric, 10)
+		exporter.buildFreezeMetrics(ch, "54321", *telemetryData)
+		return collectValues(t, ch)
+	}
+
+	values := poll(33, 0)
+	if valueOf(t, values, "omnilogic_freeze_conditions", "msp_system_id=54321") != 0 {
+		t.Fatal("Expected no freezing conditions at 33F.")
+	}
+	if valueOf(t, values, "omnilogic_freeze_threshold_degrees", "unit=fahrenheit") != 32 {
+		t.Fatal("Expected a threshold of 32F.")
+	}
+	if valueOf(t, values, "omnilogic_freeze_unprotected", "system_id=2") != 0 {
+		t.Fatal("Expected no freeze alert above the threshold.")
+	}
+	if valueOf(t, values, "omnilogic_filter_freeze_protect_override_interval_seconds", "system_id=2", "name=Filter Pump") != 7200 {
+		t.Fatal("Expected a freeze protect override interval of 7200.")
+	}
+
+	values = poll(30, 1)
+	if valueOf(t, values, "omnilogic_freeze_conditions", "msp_system_id=54321") != 1 {
+		t.Fatal("Expected freezing conditions at 30F.")
+	}
+	if valueOf(t, values, "omnilogic_freeze_filter_running", "system_id=2") != 1 || valueOf(t, values, "omnilogic_freeze_unprotected", "system_id=2") != 0 {
+		t.Fatal("Expected a running filter to protect against freezing.")
+	}
+
+	values = poll(30, 0)
+	if valueOf(t, values, "omnilogic_freeze_unprotected", "system_id=2") != 1 {
+		t.Fatal("Expected a freeze alert with the filter off.")
+	}
+
+	// Metric sites compare against the threshold in Celsius.
+	config.Backyard.Sensors = nil
+	config.System.Units = "Metric"
+	exporter.freezeThresholdCelsius = 3.5
+	values = poll(3, 1)
+	if valueOf(t, values, "omnilogic_freeze_conditions", "msp_system_id=54321") != 1 {
+		t.Fatal("Expected freezing conditions at 3C.")
+	}
+	if threshold := valueOf(t, values, "omnilogic_freeze_threshold_degrees", "unit=celsius"); math.Abs(threshold-3.5) > 1e-9 {
+		t.Fatalf("Expected a threshold of 3.5C but found %v", threshold)
+	}
+}
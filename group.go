@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	groupInfo = prometheus.NewDesc(prometheus.BuildFQName(namespace, "group", "info"),
+		"OmniLogic group (theme) from the MSP configuration.", []string{"msp_system_id", "system_id", "name", "icon_id"}, nil)
+	groupEquipmentInfo = prometheus.NewDesc(prometheus.BuildFQName(namespace, "group", "equipment_info"),
+		"Equipment controlled by an OmniLogic group and the action the group runs on it.",
+		[]string{"msp_system_id", "system_id", "name", "equipment_id", "equipment_name", "action"}, nil)
+	groupState = prometheus.NewDesc(prometheus.BuildFQName(namespace, "group", "state"),
+		"Current state of an OmniLogic group.", []string{"msp_system_id", "system_id", "name"}, nil)
+	favoriteInfo = prometheus.NewDesc(prometheus.BuildFQName(namespace, "favorite", "info"),
+		"OmniLogic favorite from the MSP configuration.",
+		[]string{"msp_system_id", "system_id", "index_id", "equipment_id", "equipment_name", "sequence", "simple_mode_enabled"}, nil)
+)
+
+// groupEquipmentIDs returns the equipment System-Ids a group request acts on.
+func groupEquipmentIDs(request Request) []string {
+	var ids []string
+	for _, parameter := range request.Parameters.Parameters {
+		switch {
+		case parameter.Name == "EquipmentID", parameter.Name == "ChlorID", parameter.Name == "LightID",
+			strings.HasPrefix(parameter.Name, "HeaterID"):
+			if id, err := strconv.Atoi(parameter.Value); err == nil && id >= 0 {
+				ids = append(ids, parameter.Value)
+			}
+		}
+	}
+	return ids
+}
+
+// buildGroupConfigMetrics exports the groups and favorites of a site's MSP
+// configuration.
+func (e *Exporter) buildGroupConfigMetrics(ch chan<- prometheus.Metric, mspSystemId string, config *MspConfig) {
+	names := config.equipmentNames()
+
+	for _, group := range config.Groups {
+		ch <- prometheus.MustNewConstMetric(groupInfo, prometheus.GaugeValue, 1, mspSystemId, group.SystemID, group.Name, group.IconID)
+
+		// A group may run several requests against the same equipment.
+		seen := map[string]bool{}
+		for _, request := range group.Requests {
+			for _, equipmentID := range groupEquipmentIDs(request) {
+				if seen[equipmentID+request.Name] {
+					continue
+				}
+				seen[equipmentID+request.Name] = true
+				ch <- prometheus.MustNewConstMetric(groupEquipmentInfo, prometheus.GaugeValue, 1, mspSystemId, group.SystemID, group.Name,
+					equipmentID, names[equipmentID], request.Name)
+			}
+		}
+	}
+
+	for _, favorite := range config.Favorites {
+		ch <- prometheus.MustNewConstMetric(favoriteInfo, prometheus.GaugeValue, 1, mspSystemId, favorite.SystemID, favorite.IndexID,
+			favorite.EquipmentOrTheme, names[favorite.EquipmentOrTheme], favorite.Sequence, favorite.SimpleModeEnabled)
+	}
+}
+
+// buildGroupStateMetrics exports the telemetry groupState of each group along
+// with its configured name.
+func (e *Exporter) buildGroupStateMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	names := map[string]string{}
+	if config, ok := e.mspConfigs[mspSystemId]; ok {
+		for _, group := range config.Groups {
+			names[group.SystemID] = group.Name
+		}
+	}
+
+	for _, item := range telemetryDataResponse.itemsNamed("group") {
+		state, err := strconv.ParseFloat(item.attributes["group_state"], 64)
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(groupState, prometheus.GaugeValue, state, mspSystemId, item.systemId, names[item.systemId])
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestGroupMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	if len(config.Groups) != 2 || len(config.Favorites) != 2 {
+		t.Fatalf("Expected 2 groups and 2 favorites but found %v and %v", len(config.Groups), len(config.Favorites))
+	}
+
+	telemetryData, err := parseTelemetryDataResponse(string(readFixture(t, "get_telemetry_data_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.mspConfigs["54321"] = config
+
+	ch := make(chan prometheus.Metric, 100)
+	exporter.buildGroupConfigMetrics(ch, "54321", config)
+	exporter.buildGroupStateMetrics(ch, "54321", *telemetryData)
+	values := collectValues(t, ch)
+
+	if valueOf(t, values, "omnilogic_group_info", "system_id=20", "name=Winter") != 1 {
+		t.Fatal("Expected Winter group info.")
+	}
+
+	if valueOf(t, values, "omnilogic_group_state", "system_id=26", "name=Decalcify") != 0 {
+		t.Fatal("Expected Decalcify group state.")
+	}
+
+	if valueOf(t, values, "omnilogic_group_equipment_info", "system_id=26", "equipment_id=23", "action=SetUITemporaryHeaterEnable") != 1 {
+		t.Fatal("Expected Decalcify group to enable heater 23.")
+	}
+
+	if valueOf(t, values, "omnilogic_favorite_info", "system_id=27", "equipment_id=26") != 1 {
+		t.Fatal("Expected favorite 27 to point at group 26.")
+	}
+
+	winterEquipment := 0
+	decalcifyEquipment := 0
+	for key := range values {
+		if containsAll(key+" ", []string{"omnilogic_group_equipment_info", " system_id=20 "}) {
+			winterEquipment++
+		}
+		if containsAll(key+" ", []string{"omnilogic_group_equipment_info", " system_id=26 "}) {
+			decalcifyEquipment++
+		}
+	}
+
+	if winterEquipment != 4 {
+		t.Fatalf("Expected Winter group to control 4 pieces of equipment but found %v", winterEquipment)
+	}
+
+	// Heaters 22 and 23 are each the target of several actions.
+	if decalcifyEquipment != 10 {
+		t.Fatalf("Expected 10 Decalcify group actions but found %v", decalcifyEquipment)
+	}
+}
+
+func TestEquipmentNames(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	names := config.equipmentNames()
+
+	expected := map[string]string{
+		"0":  "Backyard",
+		"1":  "Pool",
+		"2":  "Filter Pump",
+		"4":  "Chlorinator1",
+		"6":  "UCL",
+		"7":  "AirSensor",
+		"20": "Winter",
+		"23": "Heat Pump",
+		"24": "Bubblers",
+	}
+
+	for id, name := range expected {
+		if names[id] != name {
+			t.Fatalf("Expected equipment %v to be named %v but found %q", id, name, names[id])
+		}
+	}
+}
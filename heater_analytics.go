@@ -0,0 +1,159 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// Window over which the heating rate is measured.
+	heatingRateWindow = time.Hour
+	// Shortest span of heating samples from which a rate is derived, as the
+	// water temperature is only reported in whole degrees.
+	minHeatingRateSpan = 15 * time.Minute
+)
+
+var (
+	heaterSetpointDelta = prometheus.NewDesc(prometheus.BuildFQName(namespace, "virtual_heater", "setpoint_delta_degrees"),
+		"Set point minus the water temperature of the heated body of water.", []string{"msp_system_id", "system_id", "body_of_water"}, nil)
+	heaterHeating = prometheus.NewDesc(prometheus.BuildFQName(namespace, "virtual_heater", "heating"),
+		"Whether any heater of the virtual heater is on.", []string{"msp_system_id", "system_id", "body_of_water"}, nil)
+	heaterHeatingRate = prometheus.NewDesc(prometheus.BuildFQName(namespace, "virtual_heater", "heating_rate_degrees_per_hour"),
+		"Most recently observed rise of the water temperature while heating.", []string{"msp_system_id", "system_id", "body_of_water"}, nil)
+	heaterTimeToSetpoint = prometheus.NewDesc(prometheus.BuildFQName(namespace, "virtual_heater", "time_to_setpoint_seconds"),
+		"Estimated time until the water reaches the set point at the observed heating rate.", []string{"msp_system_id", "system_id", "body_of_water"}, nil)
+)
+
+// heatingState tracks the water temperature of a body of water while it is
+// being heated.
+type heatingState struct {
+	samples []sample
+	rate    float64
+	hasRate bool
+}
+
+// sample is a reading at a point in time.
+type sample struct {
+	time  time.Time
+	value float64
+}
+
+// observe records a water temperature and updates the heating rate. Samples
+// are only kept while heating, so each heating cycle is measured separately.
+func (h *heatingState) observe(now time.Time, temperature float64, heating bool) {
+	if !heating {
+		h.samples = nil
+		return
+	}
+
+	h.samples = append(h.samples, sample{time: now, value: temperature})
+	for len(h.samples) > 0 && now.Sub(h.samples[0].time) > heatingRateWindow {
+		h.samples = h.samples[1:]
+	}
+
+	if len(h.samples) < 2 || now.Sub(h.samples[0].time) < minHeatingRateSpan {
+		return
+	}
+
+	h.rate = slope(h.samples) * float64(time.Hour/time.Second)
+	h.hasRate = true
+}
+
+// slope returns the least squares slope of the samples in units per second.
+func slope(samples []sample) float64 {
+	origin := samples[0].time
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, sample := range samples {
+		x := sample.time.Sub(origin).Seconds()
+		y := sample.value
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// buildHeaterAnalyticsMetrics derives how far each body of water is from its
+// set point and how quickly its heaters are closing the gap.
+func (e *Exporter) buildHeaterAnalyticsMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	config, ok := e.mspConfigs[mspSystemId]
+	if !ok {
+		return
+	}
+	now := e.now()
+
+	items := map[string]TelemetryDataItem{}
+	for _, item := range telemetryDataResponse.items() {
+		items[item.name+"/"+item.systemId] = item
+	}
+
+	for _, bow := range config.Backyard.BodiesOfWater {
+		water, ok := items["body_of_water/"+bow.SystemID]
+		if !ok {
+			continue
+		}
+
+		temperature, valid := e.waterTemperature(mspSystemId, telemetryDataResponse, water)
+
+		for _, heater := range bow.Heaters {
+			virtualHeater, ok := items["virtual_heater/"+heater.SystemID]
+			if !ok {
+				continue
+			}
+
+			setpoint, err := strconv.ParseFloat(virtualHeater.attributes["current_set_point"], 64)
+			if err != nil {
+				continue
+			}
+
+			heating := false
+			for _, equipment := range heater.Equipment {
+				if state, ok := items["heater/"+equipment.SystemID].attributes["heater_state"]; ok && state != "0" {
+					heating = true
+				}
+			}
+
+			key := mspSystemId + "/" + heater.SystemID
+			state, ok := e.heating[key]
+			if !ok {
+				state = &heatingState{}
+				e.heating[key] = state
+			}
+
+			heatingValue := 0.0
+			if heating {
+				heatingValue = 1
+			}
+			ch <- prometheus.MustNewConstMetric(heaterHeating, prometheus.GaugeValue, heatingValue, mspSystemId, heater.SystemID, bow.Name)
+
+			if !valid {
+				continue
+			}
+
+			state.observe(now, temperature, heating)
+
+			delta := setpoint - temperature
+			ch <- prometheus.MustNewConstMetric(heaterSetpointDelta, prometheus.GaugeValue, delta, mspSystemId, heater.SystemID, bow.Name)
+
+			if !state.hasRate {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(heaterHeatingRate, prometheus.GaugeValue, state.rate, mspSystemId, heater.SystemID, bow.Name)
+
+			if delta <= 0 {
+				ch <- prometheus.MustNewConstMetric(heaterTimeToSetpoint, prometheus.GaugeValue, 0, mspSystemId, heater.SystemID, bow.Name)
+			} else if heating && state.rate > 0 {
+				ch <- prometheus.MustNewConstMetric(heaterTimeToSetpoint, prometheus.GaugeValue, delta/state.rate*3600, mspSystemId, heater.SystemID, bow.Name)
+			}
+		}
+	}
+}
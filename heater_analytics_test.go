@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSlope(t *testing.T) {
+	start := time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC)
+	samples := []sample{
+		{start, 80},
+		{start.Add(30 * time.Minute), 81},
+		{start.Add(60 * time.Minute), 82},
+	}
+
+	if rate := slope(samples) * 3600; math.Abs(rate-2) > 1e-9 {
+		t.Fatalf("Expected 2 degrees per hour but found %v", rate)
+	}
+
+	if rate := slope(samples[:1]); rate != 0 {
+		t.Fatalf("Expected no slope from a single sample but found %v", rate)
+	}
+}
+
+func TestHeaterAnalyticsMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	now := time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC)
+	exporter.now = func() time.Time { return now }
+	exporter.mspConfigs["54321"] = config
+
+	poll := func(waterTemp int, heaterState int) map[string]float64 {
+		telemetryData, err := parseTelemetryDataResponse(fmt.Sprintf(`<STATUS version="1.0">
+    <BodyOfWater systemId="1" flow="1" waterTemp="%d" />
+    <Filter systemId="2" filterSpeed="71" filterState="1" />
+    <VirtualHeater systemId="22" Current-Set-Point="90" enable="yes" />
+    <Heater systemId="23" heaterState="%d" enable="yes" />
+</STATUS>`, waterTemp, heaterState))
+		if err != nil {
+			t.Fatal("Error parsing telemetry data response.", err)
+		}
+		ch := make(chan prometheus.Metric, 10)
+		exporter.buildHeaterAnalyticsMetrics(ch, "54321", *telemetryData)
+		return collectValues(t, ch)
+	}
+
+	// Heating one degree every ten minutes.
+	var values map[string]float64
+	for i := 0; i <= 3; i++ {
+		poll(74+i, 1)
+		now = now.Add(5 * time.Minute)
+		values = poll(74+i, 1)
+		now = now.Add(5 * time.Minute)
+	}
+
+	if delta := valueOf(t, values, "omnilogic_virtual_heater_setpoint_delta_degrees", "system_id=22", "body_of_water=Pool"); delta != 13 {
+		t.Fatalf("Expected 13 degrees to the set point but found %v", delta)
+	}
+
+	rate := valueOf(t, values, "omnilogic_virtual_heater_heating_rate_degrees_per_hour", "system_id=22")
+	if math.Abs(rate-6) > 0.5 {
+		t.Fatalf("Expected a heating rate of about 6 degrees per hour but found %v", rate)
+	}
+
+	if seconds := valueOf(t, values, "omnilogic_virtual_heater_time_to_setpoint_seconds", "system_id=22"); math.Abs(seconds-13/rate*3600) > 1 {
+		t.Fatalf("Expected %v seconds to the set point but found %v", 13/rate*3600, seconds)
+	}
+
+	// The last rate is kept once the heater turns off, but no time to set point
+	// is estimated.
+	values = poll(77, 0)
+	if valueOf(t, values, "omnilogic_virtual_heater_heating", "system_id=22") != 0 {
+		t.Fatal("Expected the heater to be off.")
+	}
+	if valueOf(t, values, "omnilogic_virtual_heater_heating_rate_degrees_per_hour", "system_id=22") != rate {
+		t.Fatal("Expected the last heating rate to be kept.")
+	}
+	for key := range values {
+		if containsAll(key, []string{"omnilogic_virtual_heater_time_to_setpoint_seconds"}) {
+			t.Fatal("Expected no time to set point while not heating.")
+		}
+	}
+
+	// Invalid water temperatures are ignored.
+	values = poll(-1, 1)
+	for key := range values {
+		if containsAll(key, []string{"omnilogic_virtual_heater_setpoint_delta_degrees"}) {
+			t.Fatal("Expected no set point delta without a valid water temperature.")
+		}
+	}
+}
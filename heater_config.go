@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	heaterConfiguredSetpoint = prometheus.NewDesc(prometheus.BuildFQName(namespace, "virtual_heater", "configured_setpoint_degrees"),
+		"Set point of the virtual heater in the MSP configuration.", []string{"msp_system_id", "system_id", "body_of_water"}, nil)
+	heaterMaxWaterTemp = prometheus.NewDesc(prometheus.BuildFQName(namespace, "virtual_heater", "max_water_temp_degrees"),
+		"Maximum water temperature of the virtual heater.", []string{"msp_system_id", "system_id", "body_of_water"}, nil)
+	heaterSettableWaterTemp = prometheus.NewDesc(prometheus.BuildFQName(namespace, "virtual_heater", "settable_water_temp_degrees"),
+		"Lowest and highest set point that can be set on the virtual heater.", []string{"msp_system_id", "system_id", "body_of_water", "bound"}, nil)
+	heaterSetpointOutOfBounds = prometheus.NewDesc(prometheus.BuildFQName(namespace, "virtual_heater", "setpoint_out_of_bounds"),
+		"1 when the configured set point is outside the settable range or above the maximum water temperature.", []string{"msp_system_id", "system_id", "body_of_water"}, nil)
+	heaterEnabled = prometheus.NewDesc(prometheus.BuildFQName(namespace, "virtual_heater", "enabled"),
+		"Whether the virtual heater is enabled in the MSP configuration.", []string{"msp_system_id", "system_id", "body_of_water"}, nil)
+	heaterEquipmentInfo = prometheus.NewDesc(prometheus.BuildFQName(namespace, "heater_equipment", "info"),
+		"Heater operated by a virtual heater, with its type and priority.",
+		[]string{"msp_system_id", "system_id", "name", "virtual_heater_id", "heater_type", "priority", "run_for_priority"}, nil)
+	heaterEquipmentEnabled = prometheus.NewDesc(prometheus.BuildFQName(namespace, "heater_equipment", "enabled"),
+		"Whether the heater is enabled in the MSP configuration.", []string{"msp_system_id", "system_id", "name"}, nil)
+)
+
+// buildHeaterConfigMetrics exports the set point bounds of each virtual
+// heater and the heaters it operates, so misconfigured set points and
+// disabled heaters can be alerted on.
+func (e *Exporter) buildHeaterConfigMetrics(ch chan<- prometheus.Metric, mspSystemId string, config *MspConfig) {
+	for _, bow := range config.Backyard.BodiesOfWater {
+		for _, heater := range bow.Heaters {
+			ch <- prometheus.MustNewConstMetric(heaterConfiguredSetpoint, prometheus.GaugeValue, heater.CurrentSetPoint, mspSystemId, heater.SystemID, bow.Name)
+			ch <- prometheus.MustNewConstMetric(heaterMaxWaterTemp, prometheus.GaugeValue, heater.MaxWaterTemp, mspSystemId, heater.SystemID, bow.Name)
+			ch <- prometheus.MustNewConstMetric(heaterSettableWaterTemp, prometheus.GaugeValue, heater.MinSettableWaterTemp, mspSystemId, heater.SystemID, bow.Name, "min")
+			ch <- prometheus.MustNewConstMetric(heaterSettableWaterTemp, prometheus.GaugeValue, heater.MaxSettableWaterTemp, mspSystemId, heater.SystemID, bow.Name, "max")
+
+			outOfBounds := 0.0
+			if heater.CurrentSetPoint < heater.MinSettableWaterTemp || heater.CurrentSetPoint > heater.MaxSettableWaterTemp ||
+				(heater.MaxWaterTemp > 0 && heater.CurrentSetPoint > heater.MaxWaterTemp) {
+				outOfBounds = 1
+			}
+			ch <- prometheus.MustNewConstMetric(heaterSetpointOutOfBounds, prometheus.GaugeValue, outOfBounds, mspSystemId, heater.SystemID, bow.Name)
+
+			ch <- prometheus.MustNewConstMetric(heaterEnabled, prometheus.GaugeValue, yesNo(heater.Enabled), mspSystemId, heater.SystemID, bow.Name)
+
+			for _, equipment := range heater.Equipment {
+				ch <- prometheus.MustNewConstMetric(heaterEquipmentInfo, prometheus.GaugeValue, 1, mspSystemId, equipment.SystemID, equipment.Name,
+					heater.SystemID, equipment.HeaterType, equipment.Priority, equipment.RunForPriority)
+				ch <- prometheus.MustNewConstMetric(heaterEquipmentEnabled, prometheus.GaugeValue, yesNo(equipment.Enabled), mspSystemId, equipment.SystemID, equipment.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestHeaterConfigMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	build := func() map[string]float64 {
+		ch := make(chan prometheus.Metric, 20)
+		exporter.buildHeaterConfigMetrics(ch, "54321", config)
+		return collectValues(t, ch)
+	}
+
+	values := build()
+
+	if valueOf(t, values, "omnilogic_virtual_heater_configured_setpoint_degrees", "system_id=22", "body_of_water=Pool") != 90 {
+		t.Fatal("Expected a configured set point of 90.")
+	}
+	if valueOf(t, values, "omnilogic_virtual_heater_max_water_temp_degrees", "system_id=22") != 104 {
+		t.Fatal("Expected a maximum water temperature of 104.")
+	}
+	if valueOf(t, values, "omnilogic_virtual_heater_settable_water_temp_degrees", "system_id=22", "bound=min") != 55 ||
+		valueOf(t, values, "omnilogic_virtual_heater_settable_water_temp_degrees", "system_id=22", "bound=max") != 90 {
+		t.Fatal("Expected a settable range of 55 to 90.")
+	}
+	if valueOf(t, values, "omnilogic_virtual_heater_setpoint_out_of_bounds", "system_id=22") != 0 {
+		t.Fatal("Expected the set point within bounds.")
+	}
+	if valueOf(t, values, "omnilogic_virtual_heater_enabled", "system_id=22") != 1 {
+		t.Fatal("Expected the virtual heater to be enabled.")
+	}
+	if valueOf(t, values, "omnilogic_heater_equipment_info", "system_id=23", "name=Heat Pump", "virtual_heater_id=22",
+		"heater_type=HTR_HEAT_PUMP", "priority=HTR_PRIORITY_2", "run_for_priority=HTR_MAINTAINS_PRIORITY_FOR_AS_LONG_AS_VALID") != 1 {
+		t.Fatal("Expected the heat pump info.")
+	}
+	if valueOf(t, values, "omnilogic_heater_equipment_enabled", "system_id=23") != 1 {
+		t.Fatal("Expected the heat pump to be enabled.")
+	}
+
+	heater := &config.Backyard.BodiesOfWater[0].Heaters[0]
+	heater.CurrentSetPoint = 95
+	heater.Equipment[0].Enabled = "no"
+	values = build()
+
+	if valueOf(t, values, "omnilogic_virtual_heater_setpoint_out_of_bounds", "system_id=22") != 1 {
+		t.Fatal("Expected the set point above the settable range to be out of bounds.")
+	}
+	if valueOf(t, values, "omnilogic_heater_equipment_enabled", "system_id=23") != 0 {
+		t.Fatal("Expected the heat pump to be disabled.")
+	}
+}
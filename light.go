@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	lightState = prometheus.NewDesc(prometheus.BuildFQName(namespace, "light", "state"),
+		"State of the ColorLogic light, 1 for the current state. Unrecognized lightState values are exported as the unknown state.",
+		[]string{"msp_system_id", "system_id", "name", "state"}, nil)
+	lightStateValue = prometheus.NewDesc(prometheus.BuildFQName(namespace, "light", "state_value"),
+		"Raw lightState of the ColorLogic light.", []string{"msp_system_id", "system_id", "name"}, nil)
+	lightShow = prometheus.NewDesc(prometheus.BuildFQName(namespace, "light", "show"),
+		"Show of the ColorLogic light, 1 for the current show.", []string{"msp_system_id", "system_id", "name", "model", "show"}, nil)
+
+	// lightStates maps the lightState telemetry of ColorLogic lights to names.
+	// While powering on, a light shows white for 15 seconds before it starts
+	// its show.
+	lightStates = []struct {
+		value string
+		name  string
+	}{
+		{"0", "off"},
+		{"1", "powering_off"},
+		{"3", "changing_show"},
+		{"4", "powering_on"},
+		{"6", "on"},
+		{"7", "cooldown"},
+	}
+
+	colorLogic25Shows = []string{
+		"voodoo_lounge", "deep_blue_sea", "afternoon_skies", "emerald", "sangria", "cloud_white",
+		"twilight", "tranquility", "gemstone", "usa", "mardi_gras", "cool_cabaret",
+	}
+
+	// lightShows lists the show names of each ColorLogic model by currentShow.
+	lightShows = map[string][]string{
+		"COLOR_LOGIC_2_5": colorLogic25Shows,
+		"COLOR_LOGIC_4_0": colorLogic25Shows,
+		"COLOR_LOGIC_UCL": {
+			"voodoo_lounge", "deep_blue_sea", "royal_blue", "afternoon_skies", "aqua_green", "emerald",
+			"cloud_white", "warm_red", "flamingo", "vivid_violet", "sangria", "twilight",
+			"tranquility", "gemstone", "usa", "mardi_gras", "cool_cabaret",
+		},
+	}
+)
+
+// lights returns all ColorLogic lights of the site by System-Id.
+func (c *MspConfig) lights() map[string]MspEquipment {
+	lights := map[string]MspEquipment{}
+	for _, light := range c.Backyard.Lights {
+		lights[light.SystemID] = light
+	}
+	for _, bow := range c.Backyard.BodiesOfWater {
+		for _, light := range bow.Lights {
+			lights[light.SystemID] = light
+		}
+	}
+	return lights
+}
+
+// buildLightMetrics exports the state and show of each ColorLogic light by
+// name. Shows are decoded per light model, falling back to the show number
+// for unknown models and shows.
+func (e *Exporter) buildLightMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	lights := map[string]MspEquipment{}
+	if config, ok := e.mspConfigs[mspSystemId]; ok {
+		lights = config.lights()
+	}
+
+	for _, item := range telemetryDataResponse.itemsNamed("color_logic_light") {
+		light := lights[item.systemId]
+
+		if state, ok := item.attributes["light_state"]; ok {
+			recognized := false
+			for _, known := range lightStates {
+				value := 0.0
+				if known.value == state {
+					value = 1
+					recognized = true
+				}
+				ch <- prometheus.MustNewConstMetric(lightState, prometheus.GaugeValue, value, mspSystemId, item.systemId, light.Name, known.name)
+			}
+			unknown := 0.0
+			if !recognized {
+				unknown = 1
+			}
+			ch <- prometheus.MustNewConstMetric(lightState, prometheus.GaugeValue, unknown, mspSystemId, item.systemId, light.Name, "unknown")
+
+			if raw, err := strconv.ParseFloat(state, 64); err == nil {
+				ch <- prometheus.MustNewConstMetric(lightStateValue, prometheus.GaugeValue, raw, mspSystemId, item.systemId, light.Name)
+			}
+		}
+
+		show, ok := item.attributes["current_show"]
+		if !ok {
+			continue
+		}
+
+		shows := lightShows[light.Type]
+		if index, err := strconv.Atoi(show); err != nil || index < 0 || index >= len(shows) {
+			ch <- prometheus.MustNewConstMetric(lightShow, prometheus.GaugeValue, 1, mspSystemId, item.systemId, light.Name, light.Type, show)
+			continue
+		}
+
+		for i, name := range shows {
+			value := 0.0
+			if strconv.Itoa(i) == show {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(lightShow, prometheus.GaugeValue, value, mspSystemId, item.systemId, light.Name, light.Type, name)
+		}
+	}
+}
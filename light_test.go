@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLightMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.mspConfigs["54321"] = config
+
+	telemetryData, err := parseTelemetryDataResponse(`<STATUS version="1.0">
+    <Backyard systemId="54321" statusVersion="8" />
+    <ColorLogic-Light systemId="6" lightState="6" currentShow="7" />
+    <ColorLogic-Light systemId="16" lightState="9" currentShow="2" />
+</STATUS>`)
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	exporter.buildLightMetrics(ch, "54321", *telemetryData)
+	values := collectValues(t, ch)
+
+	if valueOf(t, values, "omnilogic_light_state", "system_id=6", "name=UCL", "state=on") != 1 ||
+		valueOf(t, values, "omnilogic_light_state", "system_id=6", "state=off") != 0 {
+		t.Fatal("Expected the UCL light to be on.")
+	}
+	if valueOf(t, values, "omnilogic_light_show", "system_id=6", "model=COLOR_LOGIC_UCL", "show=warm_red") != 1 ||
+		valueOf(t, values, "omnilogic_light_show", "system_id=6", "show=voodoo_lounge") != 0 {
+		t.Fatal("Expected the UCL light to show warm red.")
+	}
+
+	// Unknown lights export their show number and the unknown state.
+	if valueOf(t, values, "omnilogic_light_show", "system_id=16", "show=2") != 1 {
+		t.Fatal("Expected the show number of an unknown light.")
+	}
+	for _, state := range lightStates {
+		if valueOf(t, values, "omnilogic_light_state", "system_id=16", "state="+state.name) != 0 {
+			t.Fatalf("Expected the unknown light not to be %v.", state.name)
+		}
+	}
+	if valueOf(t, values, "omnilogic_light_state", "system_id=16", "state=unknown") != 1 ||
+		valueOf(t, values, "omnilogic_light_state", "system_id=6", "state=unknown") != 0 {
+		t.Fatal("Expected only the light with an unrecognized state to be unknown.")
+	}
+	if valueOf(t, values, "omnilogic_light_state_value", "system_id=16") != 9 ||
+		valueOf(t, values, "omnilogic_light_state_value", "system_id=6") != 6 {
+		t.Fatal("Expected the raw light states.")
+	}
+
+	if len(values) != 2*(len(lightStates)+2)+len(lightShows["COLOR_LOGIC_UCL"])+1 {
+		t.Fatalf("Expected a state and show set per light but found %v metrics", len(values))
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	maintenanceDue = prometheus.NewDesc(prometheus.BuildFQName(namespace, "maintenance", "due"),
+		"Whether the maintenance task is due.", []string{"task", "msp_system_id", "system_id"}, nil)
+	maintenanceHoursSinceService = prometheus.NewDesc(prometheus.BuildFQName(namespace, "maintenance", "hours_since_service"),
+		"Equipment runtime hours since the maintenance task was last performed.", []string{"task", "msp_system_id", "system_id"}, nil)
+	maintenanceInterval = prometheus.NewDesc(prometheus.BuildFQName(namespace, "maintenance", "interval_hours"),
+		"Equipment runtime hours between performing the maintenance task.", []string{"task", "msp_system_id", "system_id"}, nil)
+	maintenanceLastService = prometheus.NewDesc(prometheus.BuildFQName(namespace, "maintenance", "last_service_timestamp_seconds"),
+		"When the maintenance task was last performed, as seconds since the epoch.", []string{"task", "msp_system_id", "system_id"}, nil)
+)
+
+// MaintenanceLog records when each maintenance task was last performed on its
+// equipment and the equipment runtime at that moment, keyed by
+// MaintenanceConfig.key. It is optionally persisted to a file.
+type MaintenanceLog struct {
+	path     string
+	Services map[string]*serviceEvent `json:"services"`
+}
+
+type serviceEvent struct {
+	ServicedAt time.Time `json:"serviced_at"`
+	OnSeconds  float64   `json:"on_seconds"`
+}
+
+// NewMaintenanceLog returns a MaintenanceLog persisted at path, loading any
+// previously recorded service events. An empty path disables persistence.
+func NewMaintenanceLog(path string) (*MaintenanceLog, error) {
+	m := &MaintenanceLog{path: path, Services: map[string]*serviceEvent{}}
+
+	if len(path) == 0 {
+		return m, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Services == nil {
+		m.Services = map[string]*serviceEvent{}
+	}
+
+	return m, nil
+}
+
+// Save writes the service events to the state file, if persistence is enabled.
+func (m *MaintenanceLog) Save() error {
+	if len(m.path) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(m.path, data)
+}
+
+// onSeconds returns the accumulated runtime of the equipment a task applies to.
+func (e *Exporter) onSeconds(task MaintenanceConfig) float64 {
+	if runtime, ok := e.runtime.Equipment[task.MspSystemID+"/"+task.SystemID]; ok {
+		return runtime.OnSeconds
+	}
+	return 0
+}
+
+// hoursSinceService returns the equipment runtime hours since the task was
+// last performed, or since runtime tracking began if it never was.
+func (e *Exporter) hoursSinceService(task MaintenanceConfig) float64 {
+	onSeconds := e.onSeconds(task)
+	if service, ok := e.maintenance.Services[task.key()]; ok {
+		// A runtime below the one recorded at the service means the runtime
+		// was lost, e.g. without --runtime.state-file, after the service.
+		// All runtime tracked since then was accumulated after the service.
+		if onSeconds < service.OnSeconds {
+			level.Warn(e.logger).Log("msg", "Equipment runtime is below the runtime at the last service, counting from the current runtime.",
+				"task", task.Task, "MspSystemID", task.MspSystemID, "SystemID", task.SystemID)
+			service.OnSeconds = 0
+			if err := e.maintenance.Save(); err != nil {
+				level.Error(e.logger).Log("msg", "Failed to save maintenance log.", "err", err)
+			}
+		}
+		onSeconds -= service.OnSeconds
+	}
+	return onSeconds / 3600
+}
+
+// buildMaintenanceMetrics exports the state of the configured maintenance tasks.
+func (e *Exporter) buildMaintenanceMetrics(ch chan<- prometheus.Metric) {
+	for _, task := range e.config.Maintenance {
+		hours := e.hoursSinceService(task)
+
+		due := 0.0
+		if hours >= task.IntervalHours {
+			due = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(maintenanceDue, prometheus.GaugeValue, due, task.Task, task.MspSystemID, task.SystemID)
+		ch <- prometheus.MustNewConstMetric(maintenanceHoursSinceService, prometheus.GaugeValue, hours, task.Task, task.MspSystemID, task.SystemID)
+		ch <- prometheus.MustNewConstMetric(maintenanceInterval, prometheus.GaugeValue, task.IntervalHours, task.Task, task.MspSystemID, task.SystemID)
+
+		if service, ok := e.maintenance.Services[task.key()]; ok {
+			ch <- prometheus.MustNewConstMetric(maintenanceLastService, prometheus.GaugeValue, float64(service.ServicedAt.Unix()), task.Task, task.MspSystemID, task.SystemID)
+		}
+	}
+}
+
+type serviceRequest struct {
+	Task        string `json:"task"`
+	MspSystemID string `json:"msp_system_id"`
+	SystemID    string `json:"system_id"`
+}
+
+type serviceResponse struct {
+	Task              string    `json:"task"`
+	MspSystemID       string    `json:"msp_system_id"`
+	SystemID          string    `json:"system_id"`
+	ServicedAt        time.Time `json:"serviced_at"`
+	HoursSinceService float64   `json:"hours_since_service"`
+}
+
+// ServeMaintenanceService records that a maintenance task was performed,
+// resetting its hours since service. It expects a JSON body like
+// {"task": "filter-clean", "msp_system_id": "54321", "system_id": "2"}. The
+// ids may be left out when only one configured task has that name.
+func (e *Exporter) ServeMaintenanceService(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request serviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var tasks []*MaintenanceConfig
+	for i := range e.config.Maintenance {
+		task := &e.config.Maintenance[i]
+		if task.Task == request.Task &&
+			(len(request.MspSystemID) == 0 || task.MspSystemID == request.MspSystemID) &&
+			(len(request.SystemID) == 0 || task.SystemID == request.SystemID) {
+			tasks = append(tasks, task)
+		}
+	}
+	if len(tasks) == 0 {
+		http.Error(w, "Unknown maintenance task", http.StatusNotFound)
+		return
+	}
+	if len(tasks) > 1 {
+		http.Error(w, "Maintenance task is configured for more than one equipment, set msp_system_id and system_id", http.StatusBadRequest)
+		return
+	}
+	task := tasks[0]
+
+	service := &serviceEvent{ServicedAt: e.now(), OnSeconds: e.onSeconds(*task)}
+	e.maintenance.Services[task.key()] = service
+
+	if err := e.maintenance.Save(); err != nil {
+		level.Error(e.logger).Log("msg", "Failed to save maintenance log.", "err", err)
+		http.Error(w, "Failed to save maintenance log", http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(e.logger).Log("msg", "Recorded maintenance service.", "task", task.Task, "MspSystemID", task.MspSystemID, "SystemID", task.SystemID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(serviceResponse{Task: task.Task, MspSystemID: task.MspSystemID, SystemID: task.SystemID,
+		ServicedAt: service.ServicedAt, HoursSinceService: e.hoursSinceService(*task)})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMaintenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maintenance")
+	if err != nil {
+		t.Fatal("Error creating temp dir.", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tokenFile := path.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal("Error writing token file.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	now := time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC)
+	exporter.now = func() time.Time { return now }
+	exporter.config = &Config{Maintenance: []MaintenanceConfig{{Task: "filter-clean", MspSystemID: "54321", SystemID: "2", IntervalHours: 10}}}
+	exporter.runtime.Equipment["54321/2"] = &equipmentRuntime{MspSystemID: "54321", SystemID: "2", OnSeconds: 12 * 3600}
+	exporter.maintenance, err = NewMaintenanceLog(path.Join(dir, "maintenance.json"))
+	if err != nil {
+		t.Fatal("Error creating maintenance log.", err)
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	exporter.buildMaintenanceMetrics(ch)
+	values := collectValues(t, ch)
+
+	if valueOf(t, values, "omnilogic_maintenance_due", "task=filter-clean") != 1 {
+		t.Fatal("Expected filter-clean to be due after 12 hours.")
+	}
+	if valueOf(t, values, "omnilogic_maintenance_hours_since_service", "task=filter-clean") != 12 {
+		t.Fatal("Expected 12 hours since service.")
+	}
+
+	apiAuth, err := NewAPIAuth(tokenFile)
+	if err != nil {
+		t.Fatal("Error creating API auth.", err)
+	}
+	handler := apiAuth.Wrap(exporter.ServeMaintenanceService)
+
+	serve := func(token string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/maintenance/service", strings.NewReader(body))
+		if len(token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := serve("", `{"task": "filter-clean"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without a token but found %v", rec.Code)
+	}
+	if rec := serve("wrong", `{"task": "filter-clean"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 with the wrong token but found %v", rec.Code)
+	}
+	if rec := serve("s3cret", `{"task": "pump-service"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for an unknown task but found %v", rec.Code)
+	}
+
+	rec := serve("s3cret", `{"task": "filter-clean"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but found %v: %v", rec.Code, rec.Body.String())
+	}
+
+	var response serviceResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.HoursSinceService != 0 {
+		t.Fatal("Expected hours since service to be reset.", response, err)
+	}
+
+	// Two more hours of runtime after the service.
+	exporter.runtime.Equipment["54321/2"].OnSeconds += 2 * 3600
+
+	// The service event survives a restart.
+	exporter.maintenance, err = NewMaintenanceLog(path.Join(dir, "maintenance.json"))
+	if err != nil {
+		t.Fatal("Error loading maintenance log.", err)
+	}
+
+	ch = make(chan prometheus.Metric, 10)
+	exporter.buildMaintenanceMetrics(ch)
+	values = collectValues(t, ch)
+
+	if valueOf(t, values, "omnilogic_maintenance_due", "task=filter-clean") != 0 {
+		t.Fatal("Expected filter-clean not to be due after service.")
+	}
+	if valueOf(t, values, "omnilogic_maintenance_hours_since_service", "task=filter-clean") != 2 {
+		t.Fatal("Expected 2 hours since service.")
+	}
+	if valueOf(t, values, "omnilogic_maintenance_last_service_timestamp_seconds", "task=filter-clean") != float64(now.Unix()) {
+		t.Fatal("Expected last service timestamp.")
+	}
+
+	// The runtime was lost in a restart, but the service event was not.
+	exporter.runtime.Equipment["54321/2"].OnSeconds = 3 * 3600
+
+	ch = make(chan prometheus.Metric, 10)
+	exporter.buildMaintenanceMetrics(ch)
+	values = collectValues(t, ch)
+
+	if valueOf(t, values, "omnilogic_maintenance_hours_since_service", "task=filter-clean") != 3 {
+		t.Fatal("Expected the hours since service to count from the current runtime.")
+	}
+
+	exporter.runtime.Equipment["54321/2"].OnSeconds += 8 * 3600
+
+	ch = make(chan prometheus.Metric, 10)
+	exporter.buildMaintenanceMetrics(ch)
+	values = collectValues(t, ch)
+
+	if valueOf(t, values, "omnilogic_maintenance_due", "task=filter-clean") != 1 {
+		t.Fatal("Expected filter-clean to become due again after the runtime was lost.")
+	}
+}
+
+func TestMaintenancePerEquipment(t *testing.T) {
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.config = &Config{Maintenance: []MaintenanceConfig{
+		{Task: "filter-clean", MspSystemID: "54321", SystemID: "2", IntervalHours: 10},
+		{Task: "filter-clean", MspSystemID: "98765", SystemID: "2", IntervalHours: 10},
+	}}
+	exporter.runtime.Equipment["54321/2"] = &equipmentRuntime{MspSystemID: "54321", SystemID: "2", OnSeconds: 12 * 3600}
+	exporter.runtime.Equipment["98765/2"] = &equipmentRuntime{MspSystemID: "98765", SystemID: "2", OnSeconds: 12 * 3600}
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		exporter.ServeMaintenanceService(rec, httptest.NewRequest("POST", "/api/maintenance/service", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := serve(`{"task": "filter-clean"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a task configured on two filters but found %v", rec.Code)
+	}
+	if rec := serve(`{"task": "filter-clean", "msp_system_id": "54321", "system_id": "2"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but found %v: %v", rec.Code, rec.Body.String())
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	exporter.buildMaintenanceMetrics(ch)
+	values := collectValues(t, ch)
+
+	if valueOf(t, values, "omnilogic_maintenance_due", "task=filter-clean", "msp_system_id=54321") != 0 {
+		t.Fatal("Expected the serviced filter not to be due.")
+	}
+	if valueOf(t, values, "omnilogic_maintenance_due", "task=filter-clean", "msp_system_id=98765") != 1 {
+		t.Fatal("Expected the other filter to still be due.")
+	}
+}
+
+func TestAPIAuthDisabled(t *testing.T) {
+	apiAuth, err := NewAPIAuth("")
+	if err != nil {
+		t.Fatal("Error creating API auth.", err)
+	}
+
+	called := false
+	handler := apiAuth.Wrap(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	req := httptest.NewRequest("POST", "/api/maintenance/service", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if called || rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 without a configured token but found %v", rec.Code)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	manualReading = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "manual_reading"),
+		"Latest manually tested water chemistry value, in ppm except for pH.", []string{"msp_system_id", "body_of_water_system_id", "parameter"}, nil)
+	manualReadingAge = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "manual_reading_age_seconds"),
+		"Seconds since the water chemistry value was tested.", []string{"msp_system_id", "body_of_water_system_id", "parameter"}, nil)
+
+	// manualReadingParameters are the accepted test kit parameters.
+	manualReadingParameters = map[string]bool{
+		"ph":                     true,
+		"free_chlorine":          true,
+		"combined_chlorine":      true,
+		"total_alkalinity":       true,
+		"calcium_hardness":       true,
+		"cyanuric_acid":          true,
+		"total_dissolved_solids": true,
+		"salt":                   true,
+	}
+)
+
+// ManualReadings stores the latest manually tested value of each water
+// chemistry parameter per body of water. It is optionally persisted to a file.
+type ManualReadings struct {
+	path     string
+	Readings map[string]*manualReadingValue `json:"readings"`
+}
+
+type manualReadingValue struct {
+	MspSystemID         string    `json:"msp_system_id"`
+	BodyOfWaterSystemID string    `json:"body_of_water_system_id"`
+	Parameter           string    `json:"parameter"`
+	Value               float64   `json:"value"`
+	MeasuredAt          time.Time `json:"measured_at"`
+}
+
+// NewManualReadings returns ManualReadings persisted at path, loading any
+// previously stored readings. An empty path disables persistence.
+func NewManualReadings(path string) (*ManualReadings, error) {
+	m := &ManualReadings{path: path, Readings: map[string]*manualReadingValue{}}
+
+	if len(path) == 0 {
+		return m, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Readings == nil {
+		m.Readings = map[string]*manualReadingValue{}
+	}
+
+	return m, nil
+}
+
+// Save writes the readings to the state file, if persistence is enabled.
+func (m *ManualReadings) Save() error {
+	if len(m.path) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(m.path, data)
+}
+
+// Record stores a reading unless a more recent one of the same parameter
+// already exists.
+func (m *ManualReadings) Record(reading manualReadingValue) {
+	key := reading.MspSystemID + "/" + reading.BodyOfWaterSystemID + "/" + reading.Parameter
+	if existing, ok := m.Readings[key]; ok && existing.MeasuredAt.After(reading.MeasuredAt) {
+		return
+	}
+	m.Readings[key] = &reading
+}
+
+// Get returns the latest reading of a parameter of a body of water.
+func (m *ManualReadings) Get(mspSystemId string, bodyOfWaterSystemId string, parameter string) (*manualReadingValue, bool) {
+	reading, ok := m.Readings[mspSystemId+"/"+bodyOfWaterSystemId+"/"+parameter]
+	return reading, ok
+}
+
+// buildManualReadingMetrics exports the latest manual readings and their age.
+func (e *Exporter) buildManualReadingMetrics(ch chan<- prometheus.Metric) {
+	keys := make([]string, 0, len(e.manualReadings.Readings))
+	for key := range e.manualReadings.Readings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	now := e.now()
+	for _, key := range keys {
+		reading := e.manualReadings.Readings[key]
+		ch <- prometheus.MustNewConstMetric(manualReading, prometheus.GaugeValue, reading.Value, reading.MspSystemID, reading.BodyOfWaterSystemID, reading.Parameter)
+		ch <- prometheus.MustNewConstMetric(manualReadingAge, prometheus.GaugeValue, now.Sub(reading.MeasuredAt).Seconds(), reading.MspSystemID, reading.BodyOfWaterSystemID, reading.Parameter)
+	}
+}
+
+type readingsRequest struct {
+	MspSystemID         string             `json:"msp_system_id"`
+	BodyOfWaterSystemID string             `json:"body_of_water_system_id"`
+	MeasuredAt          time.Time          `json:"measured_at"`
+	Readings            map[string]float64 `json:"readings"`
+}
+
+// ServeManualReadings records manually tested water chemistry. It expects a
+// JSON body like
+// {"msp_system_id": "54321", "body_of_water_system_id": "1", "readings": {"free_chlorine": 3}}
+// with an optional RFC 3339 "measured_at" that defaults to now.
+func (e *Exporter) ServeManualReadings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request readingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(request.MspSystemID) == 0 || len(request.BodyOfWaterSystemID) == 0 {
+		http.Error(w, "msp_system_id and body_of_water_system_id are required", http.StatusBadRequest)
+		return
+	}
+	if len(request.Readings) == 0 {
+		http.Error(w, "No readings", http.StatusBadRequest)
+		return
+	}
+	for parameter, value := range request.Readings {
+		if !manualReadingParameters[parameter] {
+			http.Error(w, "Unknown parameter "+parameter, http.StatusBadRequest)
+			return
+		}
+		if value < 0 || (parameter == "ph" && value > 14) {
+			http.Error(w, "Value of "+parameter+" is out of range", http.StatusBadRequest)
+			return
+		}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	measuredAt := request.MeasuredAt
+	if measuredAt.IsZero() {
+		measuredAt = e.now()
+	}
+
+	for parameter, value := range request.Readings {
+		e.manualReadings.Record(manualReadingValue{
+			MspSystemID:         request.MspSystemID,
+			BodyOfWaterSystemID: request.BodyOfWaterSystemID,
+			Parameter:           parameter,
+			Value:               value,
+			MeasuredAt:          measuredAt,
+		})
+	}
+
+	if err := e.manualReadings.Save(); err != nil {
+		level.Error(e.logger).Log("msg", "Failed to save manual readings.", "err", err)
+		http.Error(w, "Failed to save manual readings", http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(e.logger).Log("msg", "Recorded manual readings.", "MspSystemID", request.MspSystemID, "BodyOfWaterSystemID", request.BodyOfWaterSystemID, "count", len(request.Readings))
+
+	w.WriteHeader(http.StatusNoContent)
+}
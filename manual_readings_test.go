@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestManualReadings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "readings")
+	if err != nil {
+		t.Fatal("Error creating temp dir.", err)
+	}
+	defer os.RemoveAll(dir)
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	now := time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC)
+	exporter.now = func() time.Time { return now }
+	exporter.config = &Config{Chemistry: []ChemistryConfig{{MspSystemID: "54321", BodyOfWaterSystemID: "1", CalciumHardness: new(float64)}}}
+	exporter.manualReadings, err = NewManualReadings(path.Join(dir, "readings.json"))
+	if err != nil {
+		t.Fatal("Error creating manual readings.", err)
+	}
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/readings", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		exporter.ServeManualReadings(rec, req)
+		return rec
+	}
+
+	for body, code := range map[string]int{
+		`{"body_of_water_system_id": "1", "readings": {"ph": 7.4}}`:                            http.StatusBadRequest,
+		`{"msp_system_id": "54321", "body_of_water_system_id": "1", "readings": {}}`:           http.StatusBadRequest,
+		`{"msp_system_id": "54321", "body_of_water_system_id": "1", "readings": {"iron": 1}}`:  http.StatusBadRequest,
+		`{"msp_system_id": "54321", "body_of_water_system_id": "1", "readings": {"ph": 15}}`:   http.StatusBadRequest,
+		`{"msp_system_id": "54321", "body_of_water_system_id": "1", "readings": {"salt": -1}}`: http.StatusBadRequest,
+		`not json`: http.StatusBadRequest,
+	} {
+		if rec := serve(body); rec.Code != code {
+			t.Fatalf("Expected %v for %v but found %v", code, body, rec.Code)
+		}
+	}
+
+	if rec := serve(`{"msp_system_id": "54321", "body_of_water_system_id": "1", "measured_at": "2022-04-04T10:00:00Z",
+		"readings": {"free_chlorine": 3.5, "calcium_hardness": 300}}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 but found %v: %v", rec.Code, rec.Body.String())
+	}
+
+	// An older reading does not replace a newer one.
+	if rec := serve(`{"msp_system_id": "54321", "body_of_water_system_id": "1", "measured_at": "2022-04-01T10:00:00Z",
+		"readings": {"free_chlorine": 1}}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 but found %v: %v", rec.Code, rec.Body.String())
+	}
+
+	// The readings survive a restart.
+	exporter.manualReadings, err = NewManualReadings(path.Join(dir, "readings.json"))
+	if err != nil {
+		t.Fatal("Error loading manual readings.", err)
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	exporter.buildManualReadingMetrics(ch)
+	values := collectValues(t, ch)
+
+	if len(values) != 4 {
+		t.Fatalf("Expected 4 metrics but found %v", len(values))
+	}
+	if valueOf(t, values, "omnilogic_manual_reading", "parameter=free_chlorine") != 3.5 {
+		t.Fatal("Expected a free chlorine reading of 3.5.")
+	}
+	if valueOf(t, values, "omnilogic_manual_reading_age_seconds", "parameter=free_chlorine") != 2*3600 {
+		t.Fatal("Expected a reading age of 2 hours.")
+	}
+
+	// Readings take precedence over the configuration file.
+	if value, ok := exporter.manualChemistry("54321", "1", "calcium_hardness"); !ok || value != 300 {
+		t.Fatal("Expected the calcium hardness reading of 300.", value)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+)
+
+// MspConfigResponse is the body returned by GetMspConfigFile.
+type MspConfigResponse struct {
+	XMLName   xml.Name  `xml:"Response"`
+	MspConfig MspConfig `xml:"MSPConfig"`
+}
+
+// MspConfig is the configuration of a single OmniLogic site.
+type MspConfig struct {
+	XMLName   xml.Name      `xml:"MSPConfig"`
+	System    MspSystem     `xml:"System"`
+	Backyard  MspBackyard   `xml:"Backyard"`
+	Schedules []Schedule    `xml:"Schedules>sche"`
+	Favorites []MspFavorite `xml:"Favorites>Favorite"`
+	Groups    []MspGroup    `xml:"Groups>Group"`
+
+	// When the configuration was fetched from HAAPI.
+	fetched time.Time
+}
+
+// MspSystem holds the site wide display and unit settings.
+type MspSystem struct {
+	VspSpeedFormat     string `xml:"Msp-Vsp-Speed-Format"`
+	TimeFormat         string `xml:"Msp-Time-Format"`
+	Units              string `xml:"Units"`
+	ChlorDisplay       string `xml:"Msp-Chlor-Display"`
+	Language           string `xml:"Msp-Language"`
+	UIDisplayMode      string `xml:"UI-Display-Mode"`
+	UIMoodColorEnabled string `xml:"UI-MoodColor-Enabled"`
+	UIHeaterSimpleMode string `xml:"UI-Heater-SimpleMode"`
+	UIFilterSimpleMode string `xml:"UI-Filter-SimpleMode"`
+	UILightsSimpleMode string `xml:"UI-Lights-SimpleMode"`
+}
+
+// MspBackyard is the root of a site's equipment tree.
+type MspBackyard struct {
+	SystemID      string           `xml:"System-Id"`
+	Name          string           `xml:"Name"`
+	Sensors       []MspEquipment   `xml:"Sensor"`
+	Relays        []MspRelay       `xml:"Relay"`
+	Lights        []MspEquipment   `xml:"ColorLogic-Light"`
+	BodiesOfWater []MspBodyOfWater `xml:"Body-of-water"`
+}
+
+// MspBodyOfWater is a pool or spa and the equipment attached to it. A spa
+// may share equipment with a pool and spill over into it.
+type MspBodyOfWater struct {
+	SystemID      string           `xml:"System-Id"`
+	Name          string           `xml:"Name"`
+	Type          string           `xml:"Type"`
+	SizeInGallons float64          `xml:"Size-In-Gallons"`
+	Filters       []MspPump        `xml:"Filter"`
+	Pumps         []MspPump        `xml:"Pump"`
+	Chlorinators  []MspChlorinator `xml:"Chlorinator"`
+	Relays        []MspRelay       `xml:"Relay"`
+	Lights        []MspEquipment   `xml:"ColorLogic-Light"`
+	Sensors       []MspEquipment   `xml:"Sensor"`
+	Heaters       []MspHeater      `xml:"Heater"`
+	CSADs         []MspCSAD        `xml:"CSAD"`
+
+	SharedType              string `xml:"Shared-Type"`
+	SharedPriority          string `xml:"Shared-Priority"`
+	SharedEquipmentSystemID string `xml:"Shared-Equipment-System-ID"`
+	SupportsSpillover       string `xml:"Supports-Spillover"`
+}
+
+// MspEquipment holds the settings shared by all configured equipment.
+type MspEquipment struct {
+	SystemID string `xml:"System-Id"`
+	Name     string `xml:"Name"`
+	Type     string `xml:"Type"`
+	Units    string `xml:"Units"`
+}
+
+// MspPump is a filter pump or a standalone pump. Speeds are in percent.
+type MspPump struct {
+	MspEquipment
+	FilterType         string  `xml:"Filter-Type"`
+	MaxPumpSpeed       float64 `xml:"Max-Pump-Speed"`
+	MinPumpSpeed       float64 `xml:"Min-Pump-Speed"`
+	MaxPumpRPM         float64 `xml:"Max-Pump-RPM"`
+	MinPumpRPM         float64 `xml:"Min-Pump-RPM"`
+	VspLowPumpSpeed    float64 `xml:"Vsp-Low-Pump-Speed"`
+	VspMediumPumpSpeed float64 `xml:"Vsp-Medium-Pump-Speed"`
+	VspHighPumpSpeed   float64 `xml:"Vsp-High-Pump-Speed"`
+	VspCustomPumpSpeed float64 `xml:"Vsp-Custom-Pump-Speed"`
+
+	FreezeProtectOverrideInterval float64 `xml:"Freeze-Protect-Override-Interval"`
+}
+
+// MspChlorinator is a chlorinator and the equipment it operates. The
+// SuperChlor-Timeout is in hours.
+type MspChlorinator struct {
+	MspEquipment
+	Enabled           string         `xml:"Enabled"`
+	Mode              string         `xml:"Mode"`
+	TimedPercent      float64        `xml:"Timed-Percent"`
+	SuperChlorTimeout float64        `xml:"SuperChlor-Timeout"`
+	CellType          string         `xml:"Cell-Type"`
+	SaltMonitor       string         `xml:"Salt-Monitor"`
+	DispenserType     string         `xml:"Dispenser-Type"`
+	Equipment         []MspEquipment `xml:"Operation>Chlorinator-Equipment"`
+}
+
+// MspRelay is a relay or valve actuator and the function it serves.
+type MspRelay struct {
+	MspEquipment
+	Function string `xml:"Function"`
+}
+
+// MspHeater is a virtual heater and the heaters it operates. Temperatures
+// are in the units of the site.
+type MspHeater struct {
+	MspEquipment
+	Enabled              string               `xml:"Enabled"`
+	CurrentSetPoint      float64              `xml:"Current-Set-Point"`
+	MaxWaterTemp         float64              `xml:"Max-Water-Temp"`
+	MinSettableWaterTemp float64              `xml:"Min-Settable-Water-Temp"`
+	MaxSettableWaterTemp float64              `xml:"Max-Settable-Water-Temp"`
+	Equipment            []MspHeaterEquipment `xml:"Operation>Heater-Equipment"`
+}
+
+// MspHeaterEquipment is a gas, heat pump or solar heater operated by a
+// virtual heater.
+type MspHeaterEquipment struct {
+	MspEquipment
+	HeaterType     string `xml:"Heater-Type"`
+	Enabled        string `xml:"Enabled"`
+	Priority       string `xml:"Priority"`
+	RunForPriority string `xml:"Run-For-Priority"`
+}
+
+// MspCSAD is a chemistry sense and dispense unit, which measures pH and ORP
+// and doses acid or CO2. ORP levels are in millivolts.
+type MspCSAD struct {
+	MspEquipment
+	Enabled           string  `xml:"Enabled"`
+	TargetValue       float64 `xml:"Target-Value"`
+	PHLowAlarmValue   float64 `xml:"PH-Low-Alarm-Value"`
+	PHHighAlarmValue  float64 `xml:"PH-High-Alarm-Value"`
+	ORPTargetLevel    float64 `xml:"ORP-Target-Level"`
+	ORPLowAlarmLevel  float64 `xml:"ORP-Low-Alarm-Level"`
+	ORPHighAlarmLevel float64 `xml:"ORP-High-Alarm-Level"`
+}
+
+// MspFavorite is a favorite shown in the OmniLogic app, pointing at a piece
+// of equipment or a group (theme).
+type MspFavorite struct {
+	SystemID          string `xml:"System-Id"`
+	IndexID           string `xml:"Index-Id"`
+	EquipmentOrTheme  string `xml:"EquipmentID-Or-ThemeID"`
+	Sequence          string `xml:"Sequence"`
+	Data              string `xml:"Data"`
+	SimpleModeEnabled string `xml:"SimpleModeEnabled"`
+}
+
+// MspGroup is a group (theme) of HAAPI requests run together.
+type MspGroup struct {
+	SystemID string    `xml:"System-Id"`
+	Name     string    `xml:"Name"`
+	IconID   string    `xml:"Icon-Id"`
+	Requests []Request `xml:"Request"`
+}
+
+// yesNo returns 1 for a "yes" MSP configuration value and 0 otherwise.
+func yesNo(value string) float64 {
+	if strings.EqualFold(value, "yes") {
+		return 1
+	}
+	return 0
+}
+
+// equipmentNames returns the configured names of all equipment by System-Id.
+func (c *MspConfig) equipmentNames() map[string]string {
+	names := map[string]string{}
+	add := func(equipment ...MspEquipment) {
+		for _, item := range equipment {
+			if len(item.Name) > 0 {
+				names[item.SystemID] = item.Name
+			}
+		}
+	}
+
+	add(MspEquipment{SystemID: c.Backyard.SystemID, Name: c.Backyard.Name})
+	add(c.Backyard.Sensors...)
+	for _, relay := range c.Backyard.Relays {
+		add(relay.MspEquipment)
+	}
+	add(c.Backyard.Lights...)
+
+	for _, bow := range c.Backyard.BodiesOfWater {
+		add(MspEquipment{SystemID: bow.SystemID, Name: bow.Name})
+		for _, pump := range bow.pumps() {
+			add(pump.MspEquipment)
+		}
+		for _, relay := range bow.Relays {
+			add(relay.MspEquipment)
+		}
+		add(bow.Lights...)
+		add(bow.Sensors...)
+		for _, chlorinator := range bow.Chlorinators {
+			add(chlorinator.MspEquipment)
+			add(chlorinator.Equipment...)
+		}
+		for _, heater := range bow.Heaters {
+			add(heater.MspEquipment)
+			for _, equipment := range heater.Equipment {
+				add(equipment.MspEquipment)
+			}
+		}
+		for _, csad := range bow.CSADs {
+			add(csad.MspEquipment)
+		}
+	}
+
+	for _, group := range c.Groups {
+		add(MspEquipment{SystemID: group.SystemID, Name: group.Name})
+	}
+
+	return names
+}
+
+// pumps returns the filter pumps and standalone pumps of the body of water.
+func (b *MspBodyOfWater) pumps() []MspPump {
+	return append(append([]MspPump{}, b.Filters...), b.Pumps...)
+}
+
+// pumps returns all filter pumps and standalone pumps by System-Id.
+func (c *MspConfig) pumps() map[string]MspPump {
+	pumps := map[string]MspPump{}
+	for _, bow := range c.Backyard.BodiesOfWater {
+		for _, pump := range bow.pumps() {
+			pumps[pump.SystemID] = pump
+		}
+	}
+	return pumps
+}
+
+// Schedule is a single entry of the MSP configuration Schedules section.
+type Schedule struct {
+	BowSystemID      string `xml:"bow-system-id"`
+	EquipmentID      string `xml:"equipment-id"`
+	ScheduleSystemID string `xml:"schedule-system-id"`
+	Event            string `xml:"event"`
+	Data             int    `xml:"data"`
+	Enabled          int    `xml:"enabled"`
+	StartMinute      int    `xml:"start-minute"`
+	StartHour        int    `xml:"start-hour"`
+	EndMinute        int    `xml:"end-minute"`
+	EndHour          int    `xml:"end-hour"`
+	DaysActive       int    `xml:"days-active"`
+	Recurring        int    `xml:"recurring"`
+}
+
+func (e *Exporter) buildMspConfigFileRequest(mspSystemId string) (string, error) {
+	if e.session == nil || len(e.session.UserID) == 0 {
+		return "", errors.New("session UserID is empty")
+	}
+	mspSystemIdParameter := NewParameter("int", "MspSystemID", mspSystemId)
+	versionParameter := NewParameter("int", "Version", "0")
+	parameters := []*Parameter{mspSystemIdParameter, versionParameter}
+
+	return buildRequestXml("GetMspConfigFile", parameters)
+}
+
+func parseMspConfigFileResponse(response string) (*MspConfig, error) {
+	var responseXml MspConfigResponse
+
+	if err := xml.Unmarshal([]byte(response), &responseXml); err != nil {
+		return nil, err
+	}
+
+	return &responseXml.MspConfig, nil
+}
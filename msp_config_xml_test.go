@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestBuildMspConfigFileRequest(t *testing.T) {
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.session = &Session{
+		UserID: "12345",
+	}
+
+	mspConfigFileRequest, err := exporter.buildMspConfigFileRequest("54321")
+
+	if err != nil {
+		t.Fatal("Error building MSP config file request.", err)
+	}
+
+	expectFile(t, mspConfigFileRequest, "get_msp_config_file_request.xml")
+}
+
+func TestParseMspConfigFileResponse(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	if config.System.Units != "Standard" {
+		t.Fatal("System Units was not Standard.", config.System)
+	}
+
+	if config.System.TimeFormat != "12 Hour Format" {
+		t.Fatal("System TimeFormat was not 12 Hour Format.", config.System)
+	}
+
+	if config.System.UIFilterSimpleMode != "Yes" {
+		t.Fatal("System UIFilterSimpleMode was not Yes.", config.System)
+	}
+}
+
+func TestMspConfigMetrics(t *testing.T) {
+	api := newOmnilogicAPI(map[string][][]byte{
+		"GetSiteList":      {readFixture(t, "get_site_list_response.xml")},
+		"GetMspConfigFile": {readFixture(t, "get_msp_config_file_response.xml")},
+		"GetTelemetryData": {readFixture(t, "get_telemetry_data_response.xml")},
+	})
+	defer api.Close()
+
+	exporter, err := NewExporter(api.URL, "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.session = &Session{
+		UserID: "12345",
+		Token:  "deadbeef",
+		Status: "0",
+	}
+	exporter.exportAddress = true
+
+	expectMetrics(t, exporter, "site_info.metrics",
+		prometheus.BuildFQName(namespace, "site", "info"),
+		prometheus.BuildFQName(namespace, "msp_system", "info"))
+
+	// The configuration is cached between scrapes.
+	expectMetrics(t, exporter, "site_info.metrics",
+		prometheus.BuildFQName(namespace, "site", "info"),
+		prometheus.BuildFQName(namespace, "msp_system", "info"))
+
+	if api.requests["GetMspConfigFile"] != 2 {
+		t.Fatalf("Expected one MSP config request per site but found %v", api.requests["GetMspConfigFile"])
+	}
+}
+
+func TestMspConfigUnavailable(t *testing.T) {
+	api := newOmnilogicAPI(map[string][][]byte{
+		"GetSiteList":      {readFixture(t, "get_site_list_response.xml")},
+		"GetTelemetryData": {readFixture(t, "get_telemetry_data_response.xml")},
+	})
+	defer api.Close()
+
+	exporter, err := NewExporter(api.URL, "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.session = &Session{
+		UserID: "12345",
+		Token:  "deadbeef",
+		Status: "0",
+	}
+
+	// Without any configuration the telemetry is still exported.
+	ch := make(chan prometheus.Metric, 1000)
+	if up := exporter.scrape(ch); up != 1 {
+		t.Fatal("Expected the scrape to succeed without an MSP config.")
+	}
+	values := collectValues(t, ch)
+	if valueOf(t, values, "omnilogic_backyard_air_temp", "msp_system_id=54321") != 53 {
+		t.Fatal("Expected the air temperature telemetry.")
+	}
+
+	// A stale configuration is used until it can be refreshed.
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	exporter.mspConfigs["54321"] = config
+
+	ch = make(chan prometheus.Metric, 1000)
+	if up := exporter.scrape(ch); up != 1 {
+		t.Fatal("Expected the scrape to succeed with a stale MSP config.")
+	}
+	values = collectValues(t, ch)
+	if valueOf(t, values, "omnilogic_msp_system_info", "msp_system_id=54321") != 1 {
+		t.Fatal("Expected the cached MSP config to be exported.")
+	}
+}
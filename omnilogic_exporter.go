@@ -47,6 +47,10 @@ const (
 var (
 	omnilogicUp     = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "up"), "Was the last scrape of OmniLogic successful.", nil, nil)
 	omnilogicStatus = prometheus.NewDesc(prometheus.BuildFQName(namespace, "site", "system_status"), "OmniLogic site system status.", []string{"msp_system_id", "backyard_name"}, nil)
+	sitesFiltered   = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "sites_filtered"), "Number of OmniLogic sites excluded by the site filters.", nil, nil)
+	siteInfo        = prometheus.NewDesc(prometheus.BuildFQName(namespace, "site", "info"), "OmniLogic site metadata.", []string{"msp_system_id", "backyard_name", "address"}, nil)
+	mspSystemInfo   = prometheus.NewDesc(prometheus.BuildFQName(namespace, "msp_system", "info"), "OmniLogic site system settings from the MSP configuration.",
+		[]string{"msp_system_id", "units", "time_format", "language", "chlor_display", "vsp_speed_format", "ui_display_mode", "ui_mood_color_enabled", "ui_heater_simple_mode", "ui_filter_simple_mode", "ui_lights_simple_mode"}, nil)
 )
 
 // Exporter collects OmniLogic stats from the given URI and exports them using
@@ -60,8 +64,37 @@ type Exporter struct {
 	timeout  time.Duration
 	mutex    sync.RWMutex
 
+	tokenCache       *TokenCache
+	sessionFromCache bool
+	siteFilter       *SiteFilter
+	exportAddress    bool
+
+	mspConfigs            map[string]*MspConfig
+	configRefreshInterval time.Duration
+	controllerLocation    *time.Location
+	now                   func() time.Time
+
+	schemaDriftReported map[string]bool
+
+	config          *Config
+	pumpEnergy      map[string]*integrator
+	filteredGallons map[string]*integrator
+	heating         map[string]*heatingState
+	salt            map[string]*saltState
+
+	superChlorination map[string]*superChlorinationState
+
+	waterTemperatures    map[string]*waterTemperatureState
+	holdWaterTemperature bool
+
+	freezeThresholdCelsius float64
+	runtime                *RuntimeAccumulator
+	maintenance            *MaintenanceLog
+	manualReadings         *ManualReadings
+
 	up                                            prometheus.Gauge
 	totalScrapes, xmlParseFailures, loginFailures prometheus.Counter
+	unknownAttributes, scheduleMismatches         *prometheus.CounterVec
 	logger                                        log.Logger
 }
 
@@ -78,6 +111,29 @@ func NewExporter(uri string, username string, password string, timeout time.Dura
 		userName: username,
 		password: password,
 		timeout:  timeout,
+
+		mspConfigs:            map[string]*MspConfig{},
+		configRefreshInterval: time.Hour,
+		controllerLocation:    time.Local,
+		now:                   time.Now,
+
+		schemaDriftReported: map[string]bool{},
+
+		config:          &Config{},
+		pumpEnergy:      map[string]*integrator{},
+		filteredGallons: map[string]*integrator{},
+		heating:         map[string]*heatingState{},
+		salt:            map[string]*saltState{},
+
+		superChlorination: map[string]*superChlorinationState{},
+
+		waterTemperatures: map[string]*waterTemperatureState{},
+
+		freezeThresholdCelsius: 3.5,
+		runtime:                &RuntimeAccumulator{Equipment: map[string]*equipmentRuntime{}},
+		maintenance:            &MaintenanceLog{Services: map[string]*serviceEvent{}},
+		manualReadings:         &ManualReadings{Readings: map[string]*manualReadingValue{}},
+
 		up: prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "up",
@@ -98,6 +154,16 @@ func NewExporter(uri string, username string, password string, timeout time.Dura
 			Name:      "exporter_login_failures_total",
 			Help:      "Number of errors while logging into Omnilogic.",
 		}),
+		unknownAttributes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "telemetry_unknown_attributes_total",
+			Help:      "Number of telemetry attributes seen that are not in the schema for their statusVersion.",
+		}, []string{"element", "attribute"}),
+		scheduleMismatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "schedule_mismatched_polls_total",
+			Help:      "Number of telemetry polls in which scheduled equipment was not in the state its active schedule demands.",
+		}, []string{"msp_system_id", "equipment_id"}),
 		logger: logger,
 	}, nil
 }
@@ -165,6 +231,12 @@ func (e *Exporter) Login() error {
 	case "0":
 		{
 			level.Info(e.logger).Log("msg", "Login successful.", "UserID", e.session.UserID)
+			e.sessionFromCache = false
+			if e.tokenCache != nil {
+				if err := e.tokenCache.Store(e.session); err != nil {
+					level.Warn(e.logger).Log("msg", "Failed to store session in token cache.", "err", err)
+				}
+			}
 		}
 	case "4":
 		{
@@ -179,6 +251,29 @@ func (e *Exporter) Login() error {
 	return nil
 }
 
+// RestoreSession loads a session from the token cache, if one is configured,
+// so that the first scrape can skip Login.
+func (e *Exporter) RestoreSession() {
+	if e.tokenCache == nil {
+		return
+	}
+
+	session, err := e.tokenCache.Load()
+
+	if err != nil {
+		level.Warn(e.logger).Log("msg", "Failed to load session from token cache.", "err", err)
+		return
+	}
+
+	if session == nil {
+		return
+	}
+
+	e.session = session
+	e.sessionFromCache = true
+	level.Info(e.logger).Log("msg", "Restored session from token cache.", "UserID", e.session.UserID)
+}
+
 func (e *Exporter) RefreshSiteList(ch chan<- prometheus.Metric) error {
 	siteListRequest, err := e.buildSiteListRequest()
 
@@ -211,6 +306,11 @@ func (e *Exporter) RefreshSiteList(ch chan<- prometheus.Metric) error {
 
 	level.Debug(e.logger).Log("msg", "RefreshSiteList Response Status Code", "resp.StatusCode", fmt.Sprint(resp.StatusCode))
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return fmt.Errorf("HTTP status %d: %w", resp.StatusCode, errSessionRejected)
+	}
+
 	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
 		resp.Body.Close()
 		return fmt.Errorf("HTTP status %d", resp.StatusCode)
@@ -226,21 +326,131 @@ func (e *Exporter) RefreshSiteList(ch chan<- prometheus.Metric) error {
 	level.Debug(e.logger).Log("msg", "RefreshSiteList Response Headers", "resp.Header", fmt.Sprint(resp.Header))
 	level.Debug(e.logger).Log("msg", "RefreshSiteList Response Body", "resp.Body", string(body))
 
-	e.sites, err = parseSiteListResponse(string(body))
+	sites, err := parseSiteListResponse(string(body))
 
 	if err != nil {
 		return err
 	}
 
+	var filtered []*Site
+	e.sites, filtered = e.siteFilter.Apply(sites)
+
+	for _, site := range filtered {
+		level.Debug(e.logger).Log("msg", "Site excluded by filter.", "MspSystemID", site.MspSystemID, "BackyardName", site.BackyardName)
+	}
+
 	for _, site := range e.sites {
 		ch <- prometheus.MustNewConstMetric(omnilogicStatus, prometheus.GaugeValue, site.Status, site.MspSystemID, site.BackyardName)
+
+		address := ""
+		if e.exportAddress {
+			address = site.Address
+		}
+		ch <- prometheus.MustNewConstMetric(siteInfo, prometheus.GaugeValue, 1, site.MspSystemID, site.BackyardName, address)
 	}
 
-	level.Info(e.logger).Log("msg", "Refresh site list successful.", "# Sites", len(e.sites))
+	ch <- prometheus.MustNewConstMetric(sitesFiltered, prometheus.GaugeValue, float64(len(filtered)))
+
+	level.Info(e.logger).Log("msg", "Refresh site list successful.", "# Sites", len(e.sites), "# Filtered", len(filtered))
 
 	return nil
 }
 
+// RefreshMspConfig fetches the MSP configuration of each site. Configurations
+// are cached and only fetched again once configRefreshInterval has passed.
+func (e *Exporter) RefreshMspConfig(ch chan<- prometheus.Metric) {
+
+	for _, site := range e.sites {
+		config, cached := e.mspConfigs[site.MspSystemID]
+
+		if !cached || e.now().Sub(config.fetched) >= e.configRefreshInterval {
+			fetched, err := e.fetchMspConfig(site.MspSystemID)
+
+			if err != nil {
+				// Telemetry is still exported, with the cached configuration if there is one.
+				level.Error(e.logger).Log("msg", "Failed to refresh MSP config.", "MspSystemID", site.MspSystemID, "err", err)
+				if !cached {
+					continue
+				}
+			} else {
+				config = fetched
+				e.mspConfigs[site.MspSystemID] = config
+
+				level.Info(e.logger).Log("msg", "Refresh MSP config successful.", "MspSystemID", site.MspSystemID)
+			}
+		}
+
+		system := config.System
+		ch <- prometheus.MustNewConstMetric(mspSystemInfo, prometheus.GaugeValue, 1, site.MspSystemID,
+			system.Units, system.TimeFormat, system.Language, system.ChlorDisplay, system.VspSpeedFormat, system.UIDisplayMode,
+			system.UIMoodColorEnabled, system.UIHeaterSimpleMode, system.UIFilterSimpleMode, system.UILightsSimpleMode)
+
+		e.buildScheduleMetrics(ch, site.MspSystemID, config)
+		e.buildGroupConfigMetrics(ch, site.MspSystemID, config)
+		e.buildHeaterConfigMetrics(ch, site.MspSystemID, config)
+		e.buildChlorinatorConfigMetrics(ch, site.MspSystemID, config)
+	}
+}
+
+// fetchMspConfig requests the MSP configuration of a site.
+func (e *Exporter) fetchMspConfig(mspSystemId string) (*MspConfig, error) {
+	mspConfigFileRequest, err := e.buildMspConfigFileRequest(mspSystemId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Timeout: e.timeout,
+	}
+	level.Debug(e.logger).Log("msg", "RefreshMspConfig Request Body", "mspConfigFileRequest", mspConfigFileRequest)
+	req, err := http.NewRequest("POST", e.URI, strings.NewReader(mspConfigFileRequest))
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("cache-control", "no-cache")
+	req.Header.Add("content-type", "text/xml")
+	req.Header.Add("Token", e.session.Token)
+
+	level.Debug(e.logger).Log("msg", "RefreshMspConfig Request Headers", "req.Header", fmt.Sprint(req.Header))
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	level.Debug(e.logger).Log("msg", "RefreshMspConfig Response Status Code", "resp.StatusCode", fmt.Sprint(resp.StatusCode))
+
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	level.Debug(e.logger).Log("msg", "RefreshMspConfig Response Headers", "resp.Header", fmt.Sprint(resp.Header))
+	level.Debug(e.logger).Log("msg", "RefreshMspConfig Response Body", "resp.Body", string(body))
+
+	config, err := parseMspConfigFileResponse(string(body))
+
+	if err != nil {
+		e.xmlParseFailures.Inc()
+		return nil, err
+	}
+
+	config.fetched = e.now()
+
+	return config, nil
+}
+
 func (e *Exporter) RefreshTelemetryData(ch chan<- prometheus.Metric) error {
 
 	for _, site := range e.sites {
@@ -295,16 +505,40 @@ func (e *Exporter) RefreshTelemetryData(ch chan<- prometheus.Metric) error {
 			return err
 		}
 
-		err = buildMetrics(ch, site.MspSystemID, *status)
+		err = buildMetrics(ch, site.MspSystemID, *status, e.staleWaterTemperature(site.MspSystemID, *status))
 
 		if err != nil {
 			return err
 		}
 
+		e.buildBackyardTimeMetrics(ch, site.MspSystemID, *status)
+		e.checkTelemetrySchema(ch, site.MspSystemID, *status)
+		e.buildScheduleComplianceMetrics(ch, site.MspSystemID, *status)
+		e.buildGroupStateMetrics(ch, site.MspSystemID, *status)
+		e.buildLightMetrics(ch, site.MspSystemID, *status)
+		e.buildRelayMetrics(ch, site.MspSystemID, *status)
+		e.buildCSADMetrics(ch, site.MspSystemID, *status)
+		e.buildSolarMetrics(ch, site.MspSystemID, *status)
+		e.buildSpilloverMetrics(ch, site.MspSystemID, *status)
+		e.buildPumpSpeedMetrics(ch, site.MspSystemID, *status)
+		e.buildPumpPowerMetrics(ch, site.MspSystemID, *status)
+		e.buildTurnoverMetrics(ch, site.MspSystemID, *status)
+		e.buildHeaterAnalyticsMetrics(ch, site.MspSystemID, *status)
+		e.buildWaterTemperatureMetrics(ch, site.MspSystemID, *status)
+		e.buildFreezeMetrics(ch, site.MspSystemID, *status)
+		e.buildChemistryMetrics(ch, site.MspSystemID, *status)
+		e.buildSaltTrendMetrics(ch, site.MspSystemID, *status)
+		e.buildSuperChlorinationMetrics(ch, site.MspSystemID, *status)
+		e.buildRuntimeMetrics(ch, site.MspSystemID, *status)
+
 		level.Info(e.logger).Log("msg", "Refresh telemetry data successful.")
 
 	}
 
+	if err := e.runtime.Save(); err != nil {
+		level.Warn(e.logger).Log("msg", "Failed to save equipment runtime state.", "err", err)
+	}
+
 	return nil
 }
 
@@ -372,12 +606,16 @@ func parseSiteListResponse(response string) ([]*Site, error) {
 		} // Switch parameter name
 	} // for each parameter
 	if status != "0" {
-		return nil, fmt.Errorf("received error when requesting site list: %v", statusMessage)
+		return nil, fmt.Errorf("received error when requesting site list: %v: %w", statusMessage, errSessionRejected)
 	}
 
 	return sites, nil
 }
 
+// errSessionRejected is returned when OmniLogic refuses a request made with
+// the session token, as opposed to the request failing to reach it.
+var errSessionRejected = errors.New("session rejected")
+
 type Site struct {
 	MspSystemID  string
 	BackyardName string
@@ -508,11 +746,15 @@ func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
 	defer e.mutex.Unlock()
 
 	up := e.scrape(ch)
+	e.buildMaintenanceMetrics(ch)
+	e.buildManualReadingMetrics(ch)
 
 	ch <- prometheus.MustNewConstMetric(omnilogicUp, prometheus.GaugeValue, up)
 	ch <- e.totalScrapes
 	ch <- e.xmlParseFailures
 	ch <- e.loginFailures
+	e.unknownAttributes.Collect(ch)
+	e.scheduleMismatches.Collect(ch)
 }
 
 func (e *Exporter) scrape(ch chan<- prometheus.Metric) (up float64) {
@@ -533,11 +775,33 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) (up float64) {
 	// Refresh list of Omnilogic sites and status
 	err = e.RefreshSiteList(ch)
 
+	// A cached token may have expired, so fall back to a fresh login.
+	if errors.Is(err, errSessionRejected) && e.sessionFromCache {
+		level.Warn(e.logger).Log("msg", "Cached session was rejected, logging in again.", "err", err)
+		if clearErr := e.tokenCache.Clear(); clearErr != nil {
+			level.Warn(e.logger).Log("msg", "Failed to clear token cache.", "err", clearErr)
+		}
+		e.session = nil
+		e.sessionFromCache = false
+
+		err = e.Login()
+
+		if err != nil {
+			level.Error(e.logger).Log("msg", "Can't scrape OmniLogic. Login failed.", "err", err)
+			e.loginFailures.Inc()
+			return 0
+		}
+
+		err = e.RefreshSiteList(ch)
+	}
+
 	if err != nil {
 		level.Error(e.logger).Log("msg", "Can't scrape OmniLogic. Failed to refresh site list.", "err", err)
 		return 0
 	}
 
+	e.RefreshMspConfig(ch)
+
 	err = e.RefreshTelemetryData(ch)
 
 	if err != nil {
@@ -552,12 +816,28 @@ func main() {
 
 	var (
 		webConfig         = webflag.AddFlags(kingpin.CommandLine)
+		configFile        = kingpin.Flag("config.file", "Optional exporter configuration file, e.g. for pump power curves.").Default("").String()
 		listenAddress     = kingpin.Flag("web.listen-address", "Address to listen on for web interface and telemetry.").Default(":9190").String()
 		metricsPath       = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").String()
 		omniLogicUrl      = kingpin.Flag("omnilogic.url", "The Omnilogic API URL.").Default("/metrics").Default(omnilogicUrl).String()
 		omniLogicTimeout  = kingpin.Flag("omnilogic.timeout", "Timeout for trying to get stats from OmniLogic.").Default("5s").Duration()
 		omniLogicUserName = kingpin.Flag("omnilogic.username", "UserName to login to OmniLogic.").Required().String()
 		omniLogicPassword = kingpin.Flag("omnilogic.password", "Password to login to OmniLogic.").Required().String()
+		tokenCacheFile    = kingpin.Flag("omnilogic.token-cache-file", "File in which to persist the OmniLogic session token across restarts.").Default("").String()
+		tokenCacheKeyFile = kingpin.Flag("omnilogic.token-cache-key-file", "File containing a key used to encrypt the token cache.").Default("").String()
+		siteInclude       = kingpin.Flag("omnilogic.site-include", "MspSystemID of a site to export. May be repeated; if set, other sites are ignored.").Strings()
+		siteExclude       = kingpin.Flag("omnilogic.site-exclude", "MspSystemID of a site to ignore. May be repeated.").Strings()
+		siteIncludeName   = kingpin.Flag("omnilogic.site-include-name", "Regular expression matching the BackyardName of sites to export. May be repeated.").Strings()
+		siteExcludeName   = kingpin.Flag("omnilogic.site-exclude-name", "Regular expression matching the BackyardName of sites to ignore. May be repeated.").Strings()
+		exportAddress     = kingpin.Flag("omnilogic.export-site-address", "Include the site street address in omnilogic_site_info.").Default("false").Bool()
+		holdWaterTemp     = kingpin.Flag("omnilogic.hold-last-valid-water-temperature", "Keep exporting the last valid water temperature while water is not flowing past the sensor.").Default("false").Bool()
+		freezeThresholdC  = kingpin.Flag("omnilogic.freeze-threshold-celsius", "Air temperature in Celsius at or below which a site is considered at risk of freezing. Converted to each site's units.").Default("3.5").Float64()
+		configRefresh     = kingpin.Flag("omnilogic.config-refresh-interval", "How often to fetch the MSP configuration of each site.").Default("1h").Duration()
+		runtimeStateFile  = kingpin.Flag("runtime.state-file", "File in which to persist equipment runtime counters across restarts.").Default("").String()
+		maintenanceFile   = kingpin.Flag("maintenance.state-file", "File in which to persist maintenance service events.").Default("").String()
+		readingsFile      = kingpin.Flag("manual-readings.state-file", "File in which to persist manually tested water chemistry readings.").Default("").String()
+		apiTokenFile      = kingpin.Flag("web.api-token-file", "File containing the bearer token required by the write API endpoints. The endpoints are disabled without it.").Default("").String()
+		controllerTZ      = kingpin.Flag("omnilogic.controller-timezone", "IANA timezone of the controllers' local datetime, e.g. America/New_York.").Default("Local").String()
 	)
 
 	promlogConfig := &promlog.Config{}
@@ -576,11 +856,71 @@ func main() {
 		os.Exit(1)
 	}
 
+	if len(*configFile) > 0 {
+		exporter.config, err = LoadConfig(*configFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "Error loading configuration file", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	exporter.runtime, err = NewRuntimeAccumulator(*runtimeStateFile)
+	if err != nil {
+		level.Error(logger).Log("msg", "Error loading equipment runtime state", "err", err)
+		os.Exit(1)
+	}
+
+	exporter.maintenance, err = NewMaintenanceLog(*maintenanceFile)
+	if err != nil {
+		level.Error(logger).Log("msg", "Error loading maintenance log", "err", err)
+		os.Exit(1)
+	}
+
+	exporter.manualReadings, err = NewManualReadings(*readingsFile)
+	if err != nil {
+		level.Error(logger).Log("msg", "Error loading manual readings", "err", err)
+		os.Exit(1)
+	}
+
+	apiAuth, err := NewAPIAuth(*apiTokenFile)
+	if err != nil {
+		level.Error(logger).Log("msg", "Error loading API token", "err", err)
+		os.Exit(1)
+	}
+
+	exporter.exportAddress = *exportAddress
+	exporter.holdWaterTemperature = *holdWaterTemp
+	exporter.freezeThresholdCelsius = *freezeThresholdC
+	exporter.configRefreshInterval = *configRefresh
+
+	exporter.controllerLocation, err = time.LoadLocation(*controllerTZ)
+	if err != nil {
+		level.Error(logger).Log("msg", "Error loading controller timezone", "err", err)
+		os.Exit(1)
+	}
+
+	exporter.siteFilter, err = NewSiteFilter(*siteInclude, *siteExclude, *siteIncludeName, *siteExcludeName)
+	if err != nil {
+		level.Error(logger).Log("msg", "Error parsing site filters", "err", err)
+		os.Exit(1)
+	}
+
+	if len(*tokenCacheFile) > 0 {
+		exporter.tokenCache, err = NewTokenCache(*tokenCacheFile, *tokenCacheKeyFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "Error creating token cache", "err", err)
+			os.Exit(1)
+		}
+		exporter.RestoreSession()
+	}
+
 	prometheus.MustRegister(exporter)
 	prometheus.MustRegister(version.NewCollector("omnilogic_exporter"))
 
 	level.Info(logger).Log("msg", "Listening on address", "address", *listenAddress)
 	http.Handle(*metricsPath, promhttp.Handler())
+	http.HandleFunc("/api/maintenance/service", apiAuth.Wrap(exporter.ServeMaintenanceService))
+	http.HandleFunc("/api/readings", apiAuth.Wrap(exporter.ServeManualReadings))
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
              <head><title>Omnilogic Exporter</title></head>
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"encoding/xml"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 
 	"os"
 	"path"
+	"strings"
+	"sync"
 
 	"testing"
 	"time"
@@ -14,6 +17,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 )
 
 type omnilogic struct {
@@ -33,6 +37,100 @@ func handler(h *omnilogic) http.HandlerFunc {
 	}
 }
 
+// omnilogicAPI answers each HAAPI request with the queued responses for the
+// request name, repeating the last one once the queue is exhausted.
+type omnilogicAPI struct {
+	*httptest.Server
+	mutex     sync.Mutex
+	responses map[string][][]byte
+	requests  map[string]int
+}
+
+func newOmnilogicAPI(responses map[string][][]byte) *omnilogicAPI {
+	a := &omnilogicAPI{responses: responses, requests: map[string]int{}}
+	a.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request Request
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := xml.Unmarshal(body, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+
+		queue, ok := a.responses[request.Name]
+		if !ok || len(queue) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		a.requests[request.Name]++
+		w.Write(queue[0])
+		if len(queue) > 1 {
+			a.responses[request.Name] = queue[1:]
+		}
+	}))
+	return a
+}
+
+func readFixture(t *testing.T, fixture string) []byte {
+	fixtureText, err := ioutil.ReadFile(path.Join("test", fixture))
+	if err != nil {
+		t.Fatalf("Could not open and read text fixture file, %v: %v", fixture, err)
+	}
+	return fixtureText
+}
+
+// collectValues drains ch and returns the metric values keyed by their
+// description and label values.
+func collectValues(t *testing.T, ch chan prometheus.Metric) map[string]float64 {
+	values := map[string]float64{}
+	close(ch)
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatal("Error writing metric.", err)
+		}
+		key := metric.Desc().String()
+		for _, label := range m.Label {
+			key += " " + label.GetName() + "=" + label.GetValue()
+		}
+		switch {
+		case m.Gauge != nil:
+			values[key] = m.Gauge.GetValue()
+		case m.Counter != nil:
+			values[key] = m.Counter.GetValue()
+		}
+	}
+	return values
+}
+
+// valueOf returns the value of the metric whose key contains name and all
+// of the given labels.
+func valueOf(t *testing.T, values map[string]float64, name string, labels ...string) float64 {
+	for key, value := range values {
+		parts := []string{"\"" + name + "\""}
+		for _, label := range labels {
+			parts = append(parts, " "+label+" ")
+		}
+		if !containsAll(key+" ", parts) {
+			continue
+		}
+		return value
+	}
+	t.Fatalf("Metric %v %v not found", name, labels)
+	return 0
+}
+
+func containsAll(s string, parts []string) bool {
+	for _, part := range parts {
+		if !strings.Contains(s, part) {
+			return false
+		}
+	}
+	return true
+}
+
 func handlerStale(exit chan bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		<-exit
@@ -220,3 +318,211 @@ func TestTelemetryDataRequest(t *testing.T) {
 
 	expectFile(t, telemetryDataRequest, "get_telemetry_data_request.xml")
 }
+
+func TestCachedSessionRejected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token_cache")
+	if err != nil {
+		t.Fatal("Error creating temp dir.", err)
+	}
+	defer os.RemoveAll(dir)
+
+	api := newOmnilogicAPI(map[string][][]byte{
+		"Login":            {readFixture(t, "login_response.xml")},
+		"GetSiteList":      {readFixture(t, "get_site_list_expired_response.xml"), readFixture(t, "get_site_list_response.xml")},
+		"GetMspConfigFile": {readFixture(t, "get_msp_config_file_response.xml")},
+		"GetTelemetryData": {readFixture(t, "get_telemetry_data_response.xml")},
+	})
+	defer api.Close()
+
+	cache, err := NewTokenCache(path.Join(dir, "token"), "")
+	if err != nil {
+		t.Fatal("Error creating token cache.", err)
+	}
+	if err := cache.Store(&Session{UserID: "12345", Token: "expired"}); err != nil {
+		t.Fatal("Error storing session.", err)
+	}
+
+	exporter, err := NewExporter(api.URL, "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+	exporter.tokenCache = cache
+	exporter.RestoreSession()
+
+	if exporter.session == nil || exporter.session.Token != "expired" {
+		t.Fatal("Expected session to be restored from the token cache.", exporter.session)
+	}
+
+	ch := make(chan prometheus.Metric, 1000)
+	if up := exporter.scrape(ch); up != 1 {
+		t.Fatal("Expected scrape to succeed after logging in again.")
+	}
+
+	if api.requests["Login"] != 1 {
+		t.Fatalf("Expected one login but found %v", api.requests["Login"])
+	}
+
+	session, err := cache.Load()
+	if err != nil || session == nil || session.Token != "deadbeefdeadbeefdeadbeefdeadbeef" {
+		t.Fatal("Expected token cache to hold the new session.", session, err)
+	}
+}
+
+func TestCachedSessionUnreachable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token_cache")
+	if err != nil {
+		t.Fatal("Error creating temp dir.", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The site list request fails with an HTTP error unrelated to the token.
+	api := newOmnilogicAPI(map[string][][]byte{
+		"Login": {readFixture(t, "login_response.xml")},
+	})
+	defer api.Close()
+
+	cache, err := NewTokenCache(path.Join(dir, "token"), "")
+	if err != nil {
+		t.Fatal("Error creating token cache.", err)
+	}
+	if err := cache.Store(&Session{UserID: "12345", Token: "cached"}); err != nil {
+		t.Fatal("Error storing session.", err)
+	}
+
+	exporter, err := NewExporter(api.URL, "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+	exporter.tokenCache = cache
+	exporter.RestoreSession()
+
+	ch := make(chan prometheus.Metric, 1000)
+	if up := exporter.scrape(ch); up != 0 {
+		t.Fatal("Expected scrape to fail.")
+	}
+
+	if api.requests["Login"] != 0 {
+		t.Fatalf("Expected no login but found %v", api.requests["Login"])
+	}
+
+	session, err := cache.Load()
+	if err != nil || session == nil || session.Token != "cached" {
+		t.Fatal("Expected token cache to keep the cached session.", session, err)
+	}
+}
+
+func TestSiteFilterMetrics(t *testing.T) {
+	fixtureText := readFixture(t, "get_site_list_response.xml")
+
+	exporter, err := NewExporter(newOmnilogic(fixtureText).URL, "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.session = &Session{
+		UserID: "12345",
+		Token:  "deadbeef",
+		Status: "0",
+	}
+
+	exporter.siteFilter, err = NewSiteFilter(nil, []string{"98765"}, nil, nil)
+
+	if err != nil {
+		t.Fatal("Error creating SiteFilter.", err)
+	}
+
+	expectMetrics(t, exporter, "status_filtered.metrics",
+		prometheus.BuildFQName(namespace, "site", "system_status"),
+		prometheus.BuildFQName(namespace, "", "sites_filtered"))
+}
+
+func TestSharedSystemIdMetrics(t *testing.T) {
+	// Both sites of the site list report the same telemetry, so their
+	// equipment shares every systemId.
+	api := newOmnilogicAPI(map[string][][]byte{
+		"GetSiteList":      {readFixture(t, "get_site_list_response.xml")},
+		"GetMspConfigFile": {readFixture(t, "get_msp_config_file_response.xml")},
+		"GetTelemetryData": {readFixture(t, "get_telemetry_data_response.xml")},
+	})
+	defer api.Close()
+
+	exporter, err := NewExporter(api.URL, "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.session = &Session{
+		UserID: "12345",
+		Token:  "deadbeef",
+		Status: "0",
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(exporter)
+
+	families, err := registry.Gather()
+
+	if err != nil {
+		t.Fatal("Error gathering metrics.", err)
+	}
+
+	sites := map[string]bool{}
+	for _, family := range families {
+		if family.GetName() != prometheus.BuildFQName(namespace, "backyard", "air_temp") {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "msp_system_id" {
+					sites[label.GetValue()] = true
+				}
+			}
+		}
+	}
+
+	if !sites["54321"] || !sites["98765"] {
+		t.Fatal("Expected the air temperature of both sites.", sites)
+	}
+}
+
+func TestGatherTelemetryFixtures(t *testing.T) {
+	for _, fixture := range []string{"get_telemetry_data_response.xml", "get_telemetry_data_response2.xml", "get_telemetry_data_nested_response.xml"} {
+		t.Run(fixture, func(t *testing.T) {
+			api := newOmnilogicAPI(map[string][][]byte{
+				"GetSiteList":      {readFixture(t, "get_site_list_response.xml")},
+				"GetMspConfigFile": {readFixture(t, "get_msp_config_file_response.xml")},
+				"GetTelemetryData": {readFixture(t, fixture)},
+			})
+			defer api.Close()
+
+			exporter, err := NewExporter(api.URL, "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+			if err != nil {
+				t.Fatal("Error creating Exporter.", err)
+			}
+
+			exporter.session = &Session{
+				UserID: "12345",
+				Token:  "deadbeef",
+				Status: "0",
+			}
+
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(exporter)
+
+			families, err := registry.Gather()
+
+			if err != nil {
+				t.Fatal("Error gathering metrics.", err)
+			}
+
+			for _, family := range families {
+				if family.GetName() == prometheus.BuildFQName(namespace, "", "up") && family.GetMetric()[0].GetGauge().GetValue() != 1 {
+					t.Fatal("Expected the scrape to succeed.")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// Polls further apart than this are not integrated, as the state of the
+	// equipment in between is unknown.
+	maxIntegrationInterval = 15 * time.Minute
+
+	joulesPerKWh = 3.6e6
+)
+
+var (
+	pumpPower = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pump", "power_watts"),
+		"Estimated power draw of a pump from its speed and configured power curve.", []string{"msp_system_id", "system_id", "name"}, nil)
+	pumpEnergy = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pump", "energy_joules_total"),
+		"Estimated energy used by a pump since the exporter started.", []string{"msp_system_id", "system_id", "name"}, nil)
+	pumpEnergyCost = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pump", "energy_cost_total"),
+		"Estimated cost of the energy used by a pump since the exporter started.", []string{"msp_system_id", "system_id", "name", "currency"}, nil)
+)
+
+// integrator accumulates a rate sampled at each poll using trapezoidal
+// integration over time.
+type integrator struct {
+	lastPoll  time.Time
+	lastValue float64
+	total     float64
+}
+
+// add records value at now and returns the accumulated total.
+func (i *integrator) add(now time.Time, value float64) float64 {
+	if elapsed := now.Sub(i.lastPoll); !i.lastPoll.IsZero() && elapsed > 0 && elapsed <= maxIntegrationInterval {
+		i.total += (i.lastValue + value) / 2 * elapsed.Seconds()
+	}
+	i.lastPoll = now
+	i.lastValue = value
+	return i.total
+}
+
+// hasPower reports whether the power draw of the pump is configured.
+func (p PumpConfig) hasPower() bool {
+	return len(p.PowerCurve) > 0 || p.RatedWatts > 0
+}
+
+// watts estimates the power draw of the pump at the given speed. It prefers
+// the power curve and falls back to scaling rated_watts by the cube of the
+// speed, following the pump affinity laws.
+func (p PumpConfig) watts(pump MspPump, percent float64) (float64, bool) {
+	if percent <= 0 {
+		return 0, true
+	}
+
+	rpm, haveRPM := pump.rpm(percent)
+
+	if len(p.PowerCurve) > 0 {
+		if !haveRPM {
+			return 0, false
+		}
+		curve := make([]curvePoint, len(p.PowerCurve))
+		for i, point := range p.PowerCurve {
+			curve[i] = curvePoint{rpm: point.RPM, value: point.Watts}
+		}
+		return interpolate(curve, rpm), true
+	}
+
+	ratedRPM := p.RatedRPM
+	if ratedRPM <= 0 {
+		ratedRPM = pump.MaxPumpRPM
+	}
+	if haveRPM && ratedRPM > 0 {
+		return p.RatedWatts * math.Pow(rpm/ratedRPM, 3), true
+	}
+
+	// Without RPM limits, treat the rated power as the draw at 100%.
+	return p.RatedWatts * math.Pow(percent/100, 3), true
+}
+
+// curvePoint is a value measured at a given pump speed.
+type curvePoint struct {
+	rpm, value float64
+}
+
+// interpolate linearly interpolates the curve at rpm, clamping to the first
+// and last points.
+func interpolate(curve []curvePoint, rpm float64) float64 {
+	if rpm <= curve[0].rpm {
+		return curve[0].value
+	}
+	for i := 1; i < len(curve); i++ {
+		if rpm <= curve[i].rpm {
+			low, high := curve[i-1], curve[i]
+			return low.value + (rpm-low.rpm)*(high.value-low.value)/(high.rpm-low.rpm)
+		}
+	}
+	return curve[len(curve)-1].value
+}
+
+// buildPumpPowerMetrics estimates the power draw of configured pumps and
+// integrates it into energy and cost counters.
+func (e *Exporter) buildPumpPowerMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	config, ok := e.mspConfigs[mspSystemId]
+	if !ok {
+		return
+	}
+	pumps := config.pumps()
+	now := e.now()
+
+	for _, telemetry := range pumpSpeedTelemetry {
+		for _, item := range telemetryDataResponse.itemsNamed(telemetry.element) {
+			pumpConfig, ok := e.config.pump(mspSystemId, item.systemId)
+			if !ok || !pumpConfig.hasPower() {
+				continue
+			}
+
+			speed, err := strconv.ParseFloat(item.attributes[telemetry.speedAttribute], 64)
+			if err != nil {
+				continue
+			}
+
+			pump := pumps[item.systemId]
+			watts, ok := pumpConfig.watts(pump, speed)
+			if !ok {
+				continue
+			}
+
+			key := mspSystemId + "/" + item.systemId
+			energy, ok := e.pumpEnergy[key]
+			if !ok {
+				energy = &integrator{}
+				e.pumpEnergy[key] = energy
+			}
+			joules := energy.add(now, watts)
+
+			ch <- prometheus.MustNewConstMetric(pumpPower, prometheus.GaugeValue, watts, mspSystemId, item.systemId, pump.Name)
+			ch <- prometheus.MustNewConstMetric(pumpEnergy, prometheus.CounterValue, joules, mspSystemId, item.systemId, pump.Name)
+
+			if tariff := e.config.Tariff; tariff != nil {
+				ch <- prometheus.MustNewConstMetric(pumpEnergyCost, prometheus.CounterValue, joules/joulesPerKWh*tariff.PricePerKWh,
+					mspSystemId, item.systemId, pump.Name, tariff.Currency)
+			}
+		}
+	}
+}
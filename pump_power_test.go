@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPumpWatts(t *testing.T) {
+	pump := MspPump{MinPumpSpeed: 18, MaxPumpSpeed: 100, MinPumpRPM: 600, MaxPumpRPM: 3450}
+	curve := PumpConfig{PowerCurve: []PowerPoint{{RPM: 600, Watts: 50}, {RPM: 2000, Watts: 500}, {RPM: 3450, Watts: 1700}}}
+	rated := PumpConfig{RatedWatts: 1700}
+
+	tests := []struct {
+		name    string
+		config  PumpConfig
+		pump    MspPump
+		percent float64
+		watts   float64
+	}{
+		{"off", curve, pump, 0, 0},
+		{"curve minimum", curve, pump, 18, 50},
+		{"curve maximum", curve, pump, 100, 1700},
+		{"curve interpolated", curve, pump, 59, 500 + (2025.0-2000)*1200/1450},
+		{"affinity at rated speed", rated, pump, 100, 1700},
+		{"affinity at half speed", rated, pump, 59, 1700 * math.Pow(2025.0/3450, 3)},
+		{"affinity without rpm", rated, MspPump{}, 50, 1700.0 / 8},
+	}
+
+	for _, test := range tests {
+		watts, ok := test.config.watts(test.pump, test.percent)
+		if !ok || math.Abs(watts-test.watts) > 0.001 {
+			t.Fatalf("%v: expected %v watts but found %v", test.name, test.watts, watts)
+		}
+	}
+
+	if _, ok := curve.watts(MspPump{}, 50); ok {
+		t.Fatal("Expected no estimate from a power curve without RPM limits.")
+	}
+}
+
+func TestPumpPowerMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.mspConfigs["54321"] = config
+	exporter.config = &Config{
+		Pumps:  []PumpConfig{{MspSystemID: "54321", SystemID: "2", RatedWatts: 1000, RatedRPM: 3450}},
+		Tariff: &TariffConfig{PricePerKWh: 0.5, Currency: "USD"},
+	}
+
+	now := time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC)
+	exporter.now = func() time.Time { return now }
+
+	poll := func(speed string) map[string]float64 {
+		telemetryData, err := parseTelemetryDataResponse(`<STATUS version="1.0"><Filter systemId="2" filterSpeed="` + speed + `" filterState="1" /></STATUS>`)
+		if err != nil {
+			t.Fatal("Error parsing telemetry data response.", err)
+		}
+		ch := make(chan prometheus.Metric, 10)
+		exporter.buildPumpPowerMetrics(ch, "54321", *telemetryData)
+		return collectValues(t, ch)
+	}
+
+	values := poll("100")
+	if valueOf(t, values, "omnilogic_pump_power_watts", "system_id=2", "name=Filter Pump") != 1000 {
+		t.Fatal("Expected 1000 watts at full speed.")
+	}
+	if valueOf(t, values, "omnilogic_pump_energy_joules_total", "system_id=2") != 0 {
+		t.Fatal("Expected no energy after the first poll.")
+	}
+
+	// One minute at full speed, then the pump turns off.
+	now = now.Add(time.Minute)
+	values = poll("0")
+	if valueOf(t, values, "omnilogic_pump_power_watts", "system_id=2") != 0 {
+		t.Fatal("Expected 0 watts while off.")
+	}
+	if joules := valueOf(t, values, "omnilogic_pump_energy_joules_total", "system_id=2"); joules != 30000 {
+		t.Fatalf("Expected 30000 joules but found %v", joules)
+	}
+
+	// A gap longer than the integration interval is not counted.
+	now = now.Add(time.Hour)
+	values = poll("100")
+	joules := valueOf(t, values, "omnilogic_pump_energy_joules_total", "system_id=2")
+	if joules != 30000 {
+		t.Fatalf("Expected 30000 joules after a gap but found %v", joules)
+	}
+
+	if cost := valueOf(t, values, "omnilogic_pump_energy_cost_total", "currency=USD"); math.Abs(cost-joules/3.6e6*0.5) > 1e-9 {
+		t.Fatalf("Expected cost of %v but found %v", joules/3.6e6*0.5, cost)
+	}
+}
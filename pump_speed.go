@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	filterSpeedRPM = prometheus.NewDesc(prometheus.BuildFQName(namespace, "filter", "speed_rpm"),
+		"Filter pump speed in RPM, derived from the speed percent and the configured pump limits.", []string{"msp_system_id", "system_id", "name"}, nil)
+	pumpSpeedRPM = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pump", "speed_rpm"),
+		"Pump speed in RPM, derived from the speed percent and the configured pump limits.", []string{"msp_system_id", "system_id", "name"}, nil)
+	filterPresetSpeed = prometheus.NewDesc(prometheus.BuildFQName(namespace, "filter", "vsp_preset_speed_percent"),
+		"Configured variable speed filter pump preset speeds.", []string{"msp_system_id", "system_id", "name", "preset"}, nil)
+	pumpPresetSpeed = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pump", "vsp_preset_speed_percent"),
+		"Configured variable speed pump preset speeds.", []string{"msp_system_id", "system_id", "name", "preset"}, nil)
+	filterPreset = prometheus.NewDesc(prometheus.BuildFQName(namespace, "filter", "vsp_preset"),
+		"Whether the filter pump is running at the speed of the preset.", []string{"msp_system_id", "system_id", "name", "preset"}, nil)
+	pumpPreset = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pump", "vsp_preset"),
+		"Whether the pump is running at the speed of the preset.", []string{"msp_system_id", "system_id", "name", "preset"}, nil)
+)
+
+// pumpSpeedTelemetry describes the telemetry of each kind of pump.
+var pumpSpeedTelemetry = []struct {
+	element, speedAttribute     string
+	rpm, presetSpeed, presetSet *prometheus.Desc
+}{
+	{"filter", "filter_speed", filterSpeedRPM, filterPresetSpeed, filterPreset},
+	{"pump", "pump_speed", pumpSpeedRPM, pumpPresetSpeed, pumpPreset},
+}
+
+// presets returns the configured VSP preset speeds by preset name.
+func (p MspPump) presets() map[string]float64 {
+	return map[string]float64{
+		"low":    p.VspLowPumpSpeed,
+		"medium": p.VspMediumPumpSpeed,
+		"high":   p.VspHighPumpSpeed,
+		"custom": p.VspCustomPumpSpeed,
+	}
+}
+
+// rpm maps a speed percent onto the configured RPM range. The percent range
+// between Min-Pump-Speed and Max-Pump-Speed maps linearly onto Min-Pump-RPM
+// to Max-Pump-RPM. A speed of 0 means the pump is off.
+func (p MspPump) rpm(percent float64) (float64, bool) {
+	if p.MaxPumpRPM <= 0 || p.MaxPumpSpeed <= p.MinPumpSpeed {
+		return 0, false
+	}
+	if percent <= 0 {
+		return 0, true
+	}
+	if percent < p.MinPumpSpeed {
+		percent = p.MinPumpSpeed
+	}
+	if percent > p.MaxPumpSpeed {
+		percent = p.MaxPumpSpeed
+	}
+	return p.MinPumpRPM + (percent-p.MinPumpSpeed)*(p.MaxPumpRPM-p.MinPumpRPM)/(p.MaxPumpSpeed-p.MinPumpSpeed), true
+}
+
+// buildPumpSpeedMetrics exports filter and pump speeds in RPM and the VSP
+// presets they are running at.
+func (e *Exporter) buildPumpSpeedMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	config, ok := e.mspConfigs[mspSystemId]
+	if !ok {
+		return
+	}
+	pumps := config.pumps()
+
+	for _, telemetry := range pumpSpeedTelemetry {
+		for _, item := range telemetryDataResponse.itemsNamed(telemetry.element) {
+			pump, ok := pumps[item.systemId]
+			if !ok {
+				continue
+			}
+
+			speed, err := strconv.ParseFloat(item.attributes[telemetry.speedAttribute], 64)
+			if err != nil {
+				continue
+			}
+
+			if rpm, ok := pump.rpm(speed); ok {
+				ch <- prometheus.MustNewConstMetric(telemetry.rpm, prometheus.GaugeValue, rpm, mspSystemId, item.systemId, pump.Name)
+			}
+
+			for preset, presetSpeed := range pump.presets() {
+				if presetSpeed <= 0 {
+					continue
+				}
+				running := 0.0
+				if speed > 0 && speed == presetSpeed {
+					running = 1
+				}
+				ch <- prometheus.MustNewConstMetric(telemetry.presetSpeed, prometheus.GaugeValue, presetSpeed, mspSystemId, item.systemId, pump.Name, preset)
+				ch <- prometheus.MustNewConstMetric(telemetry.presetSet, prometheus.GaugeValue, running, mspSystemId, item.systemId, pump.Name, preset)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPumpRPM(t *testing.T) {
+	pump := MspPump{MinPumpSpeed: 18, MaxPumpSpeed: 100, MinPumpRPM: 600, MaxPumpRPM: 3450}
+
+	tests := []struct {
+		percent, rpm float64
+	}{
+		{0, 0},
+		{10, 600},
+		{18, 600},
+		{59, 2025},
+		{100, 3450},
+		{120, 3450},
+	}
+
+	for _, test := range tests {
+		rpm, ok := pump.rpm(test.percent)
+		if !ok || math.Abs(rpm-test.rpm) > 0.001 {
+			t.Fatalf("Expected %v%% to be %v RPM but found %v", test.percent, test.rpm, rpm)
+		}
+	}
+
+	if _, ok := (MspPump{}).rpm(50); ok {
+		t.Fatal("Expected no RPM without configured pump limits.")
+	}
+}
+
+func TestPumpSpeedMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	// Add a standalone pump, the fixture only has a filter pump.
+	config.Backyard.BodiesOfWater[0].Pumps = []MspPump{{
+		MspEquipment:     MspEquipment{SystemID: "4", Name: "Waterfall"},
+		MinPumpSpeed:     20,
+		MaxPumpSpeed:     100,
+		MinPumpRPM:       1000,
+		MaxPumpRPM:       3000,
+		VspHighPumpSpeed: 100,
+	}}
+
+	telemetryData, err := parseTelemetryDataResponse(`<STATUS version="1.0">
+    <Filter systemId="2" valvePosition="1" filterSpeed="71" filterState="1" lastSpeed="71" />
+    <Pump systemId="4" pumpState="1" pumpSpeed="60" lastSpeed="100" />
+</STATUS>`)
+
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.mspConfigs["54321"] = config
+
+	ch := make(chan prometheus.Metric, 100)
+	exporter.buildPumpSpeedMetrics(ch, "54321", *telemetryData)
+	values := collectValues(t, ch)
+
+	expectedRPM := 600 + (71.0-18)*(3450-600)/(100-18)
+	if rpm := valueOf(t, values, "omnilogic_filter_speed_rpm", "system_id=2", "name=Filter Pump"); math.Abs(rpm-expectedRPM) > 0.001 {
+		t.Fatalf("Expected filter speed of %v RPM but found %v", expectedRPM, rpm)
+	}
+
+	if rpm := valueOf(t, values, "omnilogic_pump_speed_rpm", "system_id=4"); rpm != 2000 {
+		t.Fatalf("Expected pump speed of 2000 RPM but found %v", rpm)
+	}
+
+	if valueOf(t, values, "omnilogic_filter_vsp_preset", "system_id=2", "preset=custom") != 1 {
+		t.Fatal("Expected filter pump to run at the custom preset.")
+	}
+
+	if valueOf(t, values, "omnilogic_filter_vsp_preset", "system_id=2", "preset=medium") != 0 {
+		t.Fatal("Expected filter pump not to run at the medium preset.")
+	}
+
+	if valueOf(t, values, "omnilogic_filter_vsp_preset_speed_percent", "system_id=2", "preset=low") != 18 {
+		t.Fatal("Expected filter pump low preset of 18%.")
+	}
+
+	if valueOf(t, values, "omnilogic_pump_vsp_preset", "system_id=4", "preset=high") != 0 {
+		t.Fatal("Expected pump not to run at the high preset.")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const valveActuatorType = "RLY_VALVE_ACTUATOR"
+
+var (
+	relayOn = prometheus.NewDesc(prometheus.BuildFQName(namespace, "relay", "on"),
+		"Whether the relay is on, with its configured function and type.", []string{"msp_system_id", "system_id", "name", "function", "type"}, nil)
+	valveActuatorPosition = prometheus.NewDesc(prometheus.BuildFQName(namespace, "valve_actuator", "position"),
+		"Position of the valve actuator, 1 when actuated and 0 when at rest.", []string{"msp_system_id", "system_id", "name", "function"}, nil)
+)
+
+// relays returns all relays and valve actuators of the site by System-Id.
+func (c *MspConfig) relays() map[string]MspRelay {
+	relays := map[string]MspRelay{}
+	for _, relay := range c.Backyard.Relays {
+		relays[relay.SystemID] = relay
+	}
+	for _, bow := range c.Backyard.BodiesOfWater {
+		for _, relay := range bow.Relays {
+			relays[relay.SystemID] = relay
+		}
+	}
+	return relays
+}
+
+// buildRelayMetrics exports the relayState of each relay joined with its
+// configured name, function and type. Valve actuators are also exported as
+// positions, apart from the high voltage relays.
+func (e *Exporter) buildRelayMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	relays := map[string]MspRelay{}
+	if config, ok := e.mspConfigs[mspSystemId]; ok {
+		relays = config.relays()
+	}
+
+	for _, item := range telemetryDataResponse.itemsNamed("relay") {
+		state, ok := item.attributes["relay_state"]
+		if !ok {
+			continue
+		}
+		relay := relays[item.systemId]
+
+		on := 0.0
+		if state != "0" {
+			on = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(relayOn, prometheus.GaugeValue, on, mspSystemId, item.systemId, relay.Name, relay.Function, relay.Type)
+		if relay.Type == valveActuatorType {
+			ch <- prometheus.MustNewConstMetric(valveActuatorPosition, prometheus.GaugeValue, on, mspSystemId, item.systemId, relay.Name, relay.Function)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRelayMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	if relay := config.relays()["24"]; relay.Name != "Bubblers" || relay.Function != "RLY_FOUNTAIN" {
+		t.Fatal("Expected relay 24 to be the Bubblers fountain.", relay)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.mspConfigs["54321"] = config
+	config.Backyard.Relays = append(config.Backyard.Relays, MspRelay{
+		MspEquipment: MspEquipment{SystemID: "30", Name: "Landscape", Type: "RLY_HIGH_VOLTAGE_RELAY"},
+		Function:     "RLY_LIGHT",
+	})
+
+	telemetryData, err := parseTelemetryDataResponse(`<STATUS version="1.0">
+    <Backyard systemId="54321" statusVersion="8" />
+    <Relay systemId="5" relayState="1" />
+    <Relay systemId="24" relayState="0" />
+    <Relay systemId="30" relayState="1" />
+</STATUS>`)
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	exporter.buildRelayMetrics(ch, "54321", *telemetryData)
+	values := collectValues(t, ch)
+
+	if len(values) != 5 {
+		t.Fatalf("Expected 3 relays and 2 valve actuators but found %v metrics", len(values))
+	}
+	if valueOf(t, values, "omnilogic_relay_on", "name=Fountain", "function=RLY_WATER_FEATURE", "type=RLY_VALVE_ACTUATOR") != 1 {
+		t.Fatal("Expected the Fountain relay to be on.")
+	}
+	if valueOf(t, values, "omnilogic_valve_actuator_position", "name=Fountain", "function=RLY_WATER_FEATURE") != 1 {
+		t.Fatal("Expected the Fountain valve actuator to be actuated.")
+	}
+	if valueOf(t, values, "omnilogic_valve_actuator_position", "name=Bubblers", "function=RLY_FOUNTAIN") != 0 {
+		t.Fatal("Expected the Bubblers valve actuator to be at rest.")
+	}
+	if valueOf(t, values, "omnilogic_relay_on", "name=Landscape", "type=RLY_HIGH_VOLTAGE_RELAY") != 1 {
+		t.Fatal("Expected the Landscape relay to be on.")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// Chlorinator status bit set while the cell is generating chlorine, as in
+	// the ChlorinatorStatus flags of python-omnilogic-local
+	// (pyomnilogic_local/omnitypes.py).
+	chlorinatorStatusGenerating = 4
+)
+
+var (
+	equipmentOnSeconds = prometheus.NewDesc(prometheus.BuildFQName(namespace, "equipment", "on_seconds_total"),
+		"Time the equipment has been on, accumulated between polls and persisted across restarts.", []string{"msp_system_id", "system_id", "element", "name"}, nil)
+)
+
+// runtimeStateAttributes maps the telemetry elements whose runtime is tracked
+// to the attribute holding their state. Any non-zero state counts as on.
+var runtimeStateAttributes = map[string]string{
+	"filter":            "filter_state",
+	"pump":              "pump_state",
+	"heater":            "heater_state",
+	"relay":             "relay_state",
+	"color_logic_light": "light_state",
+	"chlorinator":       "status",
+}
+
+// equipmentOn reports whether a telemetry item is on, and whether its state
+// could be determined at all.
+func equipmentOn(item TelemetryDataItem) (on bool, known bool) {
+	attribute, ok := runtimeStateAttributes[item.name]
+	if !ok {
+		return false, false
+	}
+
+	state, err := strconv.Atoi(item.attributes[attribute])
+	if err != nil {
+		return false, false
+	}
+
+	if item.name == "chlorinator" {
+		return state&chlorinatorStatusGenerating != 0, true
+	}
+
+	return state != 0, true
+}
+
+// RuntimeAccumulator integrates the time each piece of equipment is on. The
+// totals are optionally persisted to a file so they survive restarts.
+type RuntimeAccumulator struct {
+	path      string
+	Equipment map[string]*equipmentRuntime `json:"equipment"`
+}
+
+type equipmentRuntime struct {
+	MspSystemID string  `json:"msp_system_id"`
+	SystemID    string  `json:"system_id"`
+	Element     string  `json:"element"`
+	OnSeconds   float64 `json:"on_seconds"`
+
+	lastPoll time.Time
+	lastOn   bool
+}
+
+// NewRuntimeAccumulator returns a RuntimeAccumulator persisted at path, loading
+// any previously saved totals. An empty path disables persistence.
+func NewRuntimeAccumulator(path string) (*RuntimeAccumulator, error) {
+	r := &RuntimeAccumulator{path: path, Equipment: map[string]*equipmentRuntime{}}
+
+	if len(path) == 0 {
+		return r, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	if r.Equipment == nil {
+		r.Equipment = map[string]*equipmentRuntime{}
+	}
+
+	return r, nil
+}
+
+// Observe records the state of a piece of equipment at the given time. The
+// interval since the previous poll counts as on if the equipment was on then.
+func (r *RuntimeAccumulator) Observe(mspSystemId string, item TelemetryDataItem, on bool, now time.Time) *equipmentRuntime {
+	key := mspSystemId + "/" + item.systemId
+	runtime, ok := r.Equipment[key]
+	if !ok {
+		runtime = &equipmentRuntime{MspSystemID: mspSystemId, SystemID: item.systemId}
+		r.Equipment[key] = runtime
+	}
+	runtime.Element = item.name
+
+	if elapsed := now.Sub(runtime.lastPoll); !runtime.lastPoll.IsZero() && runtime.lastOn && elapsed > 0 && elapsed <= maxIntegrationInterval {
+		runtime.OnSeconds += elapsed.Seconds()
+	}
+	runtime.lastPoll = now
+	runtime.lastOn = on
+
+	return runtime
+}
+
+// Save writes the totals to the state file, if persistence is enabled.
+func (r *RuntimeAccumulator) Save() error {
+	if len(r.path) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(r.path, data)
+}
+
+// buildRuntimeMetrics accumulates the on time of the site's equipment.
+func (e *Exporter) buildRuntimeMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	names := map[string]string{}
+	if config, ok := e.mspConfigs[mspSystemId]; ok {
+		names = config.equipmentNames()
+	}
+	now := e.now()
+
+	for _, item := range telemetryDataResponse.items() {
+		on, known := equipmentOn(item)
+		if !known {
+			continue
+		}
+
+		runtime := e.runtime.Observe(mspSystemId, item, on, now)
+		ch <- prometheus.MustNewConstMetric(equipmentOnSeconds, prometheus.CounterValue, runtime.OnSeconds, mspSystemId, item.systemId, item.name, names[item.systemId])
+	}
+}
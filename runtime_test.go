@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestEquipmentOn(t *testing.T) {
+	telemetryData, err := parseTelemetryDataResponse(string(readFixture(t, "get_telemetry_data_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	expected := map[string]bool{
+		"2":  true,  // Filter
+		"23": true,  // Heater
+		"3":  false, // Chlorinator status 128 is not generating
+		"5":  false, // Relay
+		"24": false, // Relay
+		"6":  false, // ColorLogic-Light
+	}
+
+	for _, item := range telemetryData.DataItems {
+		on, known := equipmentOn(item)
+		want, tracked := expected[item.systemId]
+		if known != tracked {
+			t.Fatalf("Expected tracking of %v %v to be %v", item.name, item.systemId, tracked)
+		}
+		if tracked && on != want {
+			t.Fatalf("Expected %v %v to be on=%v", item.name, item.systemId, want)
+		}
+	}
+}
+
+func TestRuntimeMetricsPersisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runtime")
+	if err != nil {
+		t.Fatal("Error creating temp dir.", err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := path.Join(dir, "runtime.json")
+
+	poll := func(exporter *Exporter, filterState string) map[string]float64 {
+		telemetryData, err := parseTelemetryDataResponse(`<STATUS version="1.0"><Filter systemId="2" filterSpeed="71" filterState="` + filterState + `" /></STATUS>`)
+		if err != nil {
+			t.Fatal("Error parsing telemetry data response.", err)
+		}
+		ch := make(chan prometheus.Metric, 10)
+		exporter.buildRuntimeMetrics(ch, "54321", *telemetryData)
+		if err := exporter.runtime.Save(); err != nil {
+			t.Fatal("Error saving runtime state.", err)
+		}
+		return collectValues(t, ch)
+	}
+
+	newExporter := func(now *time.Time) *Exporter {
+		exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+		if err != nil {
+			t.Fatal("Error creating Exporter.", err)
+		}
+		exporter.runtime, err = NewRuntimeAccumulator(stateFile)
+		if err != nil {
+			t.Fatal("Error loading runtime state.", err)
+		}
+		exporter.now = func() time.Time { return *now }
+		return exporter
+	}
+
+	now := time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC)
+	exporter := newExporter(&now)
+
+	poll(exporter, "1")
+	now = now.Add(5 * time.Minute)
+	poll(exporter, "0")
+	now = now.Add(5 * time.Minute)
+	values := poll(exporter, "1")
+
+	if seconds := valueOf(t, values, "omnilogic_equipment_on_seconds_total", "system_id=2", "element=filter"); seconds != 300 {
+		t.Fatalf("Expected 300 seconds on but found %v", seconds)
+	}
+
+	// A restarted exporter continues from the persisted total.
+	exporter = newExporter(&now)
+	now = now.Add(5 * time.Minute)
+	values = poll(exporter, "1")
+
+	if seconds := valueOf(t, values, "omnilogic_equipment_on_seconds_total", "system_id=2"); seconds != 300 {
+		t.Fatalf("Expected 300 seconds on after restart but found %v", seconds)
+	}
+
+	now = now.Add(time.Minute)
+	values = poll(exporter, "1")
+
+	if seconds := valueOf(t, values, "omnilogic_equipment_on_seconds_total", "system_id=2"); seconds != 360 {
+		t.Fatalf("Expected 360 seconds on but found %v", seconds)
+	}
+
+	info, err := os.Stat(stateFile)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatal("Expected runtime state file with 0600 permissions.", err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// Window over which the salt change rate is measured. Dilution from rain
+	// or a leak shows over days, not hours.
+	saltTrendWindow = 72 * time.Hour
+	// Shortest span of samples from which a change rate is derived.
+	minSaltTrendSpan = 6 * time.Hour
+	// Window in which a fall of the average salt level counts as sudden.
+	saltSuddenDropWindow = 6 * time.Hour
+	// Fall of the average salt level within saltSuddenDropWindow that is
+	// considered a sudden drop.
+	saltSuddenDropPPM = 300
+	// Relative difference between the instant and average salt level above
+	// which the readings are considered diverging.
+	saltDivergenceRatio = 0.15
+)
+
+var (
+	saltChangeRate = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chlorinator", "salt_change_ppm_per_day"),
+		"Change of the average salt level over the trend window.", []string{"msp_system_id", "system_id", "name"}, nil)
+	saltDivergence = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chlorinator", "salt_divergence_ratio"),
+		"Instant minus average salt level, relative to the average salt level.", []string{"msp_system_id", "system_id", "name"}, nil)
+	saltAnomaly = prometheus.NewDesc(prometheus.BuildFQName(namespace, "chlorinator", "salt_anomaly"),
+		"Whether a salt anomaly is present, by anomaly: sudden_drop or divergence.", []string{"msp_system_id", "system_id", "name", "anomaly"}, nil)
+
+	saltAnomalies = []string{"sudden_drop", "divergence"}
+)
+
+// saltState tracks the salt levels of a chlorinator.
+type saltState struct {
+	samples   []sample
+	rate      float64
+	hasRate   bool
+	anomalies map[string]bool
+}
+
+// observe records the average and instant salt level and returns the
+// anomalies present now.
+func (s *saltState) observe(now time.Time, average float64, instant float64) map[string]bool {
+	s.samples = append(s.samples, sample{time: now, value: average})
+	for len(s.samples) > 0 && now.Sub(s.samples[0].time) > saltTrendWindow {
+		s.samples = s.samples[1:]
+	}
+
+	if len(s.samples) >= 2 && now.Sub(s.samples[0].time) >= minSaltTrendSpan {
+		s.rate = slope(s.samples) * float64(24*time.Hour/time.Second)
+		s.hasRate = true
+	}
+
+	peak := average
+	for _, sample := range s.samples {
+		if now.Sub(sample.time) <= saltSuddenDropWindow && sample.value > peak {
+			peak = sample.value
+		}
+	}
+
+	return map[string]bool{
+		"sudden_drop": peak-average >= saltSuddenDropPPM,
+		"divergence":  math.Abs(instant-average) > saltDivergenceRatio*average,
+	}
+}
+
+// buildSaltTrendMetrics exports the salt level trend of each chlorinator and
+// flags sudden drops and diverging instant and average readings.
+func (e *Exporter) buildSaltTrendMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	names := map[string]string{}
+	if config, ok := e.mspConfigs[mspSystemId]; ok {
+		names = config.equipmentNames()
+	}
+	now := e.now()
+
+	for _, item := range telemetryDataResponse.itemsNamed("chlorinator") {
+		average, err := strconv.ParseFloat(item.attributes["avg_salt_level"], 64)
+		if err != nil || average <= 0 {
+			continue
+		}
+		instant, err := strconv.ParseFloat(item.attributes["instant_salt_level"], 64)
+		if err != nil || instant <= 0 {
+			continue
+		}
+		name := names[item.systemId]
+
+		key := mspSystemId + "/" + item.systemId
+		state, ok := e.salt[key]
+		if !ok {
+			state = &saltState{anomalies: map[string]bool{}}
+			e.salt[key] = state
+		}
+
+		anomalies := state.observe(now, average, instant)
+		for _, anomaly := range saltAnomalies {
+			present := anomalies[anomaly]
+			if present && !state.anomalies[anomaly] {
+				level.Warn(e.logger).Log("msg", "Salt anomaly detected.", "anomaly", anomaly, "MspSystemID", mspSystemId, "SystemID", item.systemId, "avgSaltLevel", average, "instantSaltLevel", instant)
+			} else if !present && state.anomalies[anomaly] {
+				level.Info(e.logger).Log("msg", "Salt anomaly cleared.", "anomaly", anomaly, "MspSystemID", mspSystemId, "SystemID", item.systemId, "avgSaltLevel", average, "instantSaltLevel", instant)
+			}
+			state.anomalies[anomaly] = present
+
+			value := 0.0
+			if present {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(saltAnomaly, prometheus.GaugeValue, value, mspSystemId, item.systemId, name, anomaly)
+		}
+
+		ch <- prometheus.MustNewConstMetric(saltDivergence, prometheus.GaugeValue, (instant-average)/average, mspSystemId, item.systemId, name)
+		if state.hasRate {
+			ch <- prometheus.MustNewConstMetric(saltChangeRate, prometheus.GaugeValue, state.rate, mspSystemId, item.systemId, name)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSaltTrendMetrics(t *testing.T) {
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	now := time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC)
+	exporter.now = func() time.Time { return now }
+
+	poll := func(average int, instant int) map[string]float64 {
+		telemetryData, err := parseTelemetryDataResponse(fmt.Sprintf(`<STATUS version="1.0">
+    <Backyard systemId="54321" statusVersion="8" />
+    <Chlorinator systemId="3" operatingMode="1" scMode="0" avgSaltLevel="%d" instantSaltLevel="%d" status="128" />
+</STATUS>`, average, instant))
+		if err != nil {
+			t.Fatal("Error parsing telemetry data response.", err)
+		}
+		ch := make(chan prometheus.Metric, 10)
+		exporter.buildSaltTrendMetrics(ch, "54321", *telemetryData)
+		return collectValues(t, ch)
+	}
+
+	// No change rate until the samples span the minimum trend span.
+	values := poll(3200, 3150)
+	if len(values) != 3 {
+		t.Fatalf("Expected 3 metrics before a trend is known but found %v", len(values))
+	}
+	if divergence := valueOf(t, values, "omnilogic_chlorinator_salt_divergence_ratio", "system_id=3"); math.Abs(divergence-(-50.0/3200)) > 1e-9 {
+		t.Fatalf("Expected a divergence of -50/3200 but found %v", divergence)
+	}
+
+	// Rain dilutes the pool by 50 ppm per day.
+	for hour := 1; hour <= 24; hour++ {
+		now = now.Add(time.Hour)
+		level := 3200 - 50*hour/24
+		values = poll(level, level)
+	}
+	if rate := valueOf(t, values, "omnilogic_chlorinator_salt_change_ppm_per_day", "system_id=3"); math.Abs(rate-(-50)) > 1 {
+		t.Fatalf("Expected a change of -50 ppm per day but found %v", rate)
+	}
+	if valueOf(t, values, "omnilogic_chlorinator_salt_anomaly", "anomaly=sudden_drop") != 0 ||
+		valueOf(t, values, "omnilogic_chlorinator_salt_anomaly", "anomaly=divergence") != 0 {
+		t.Fatal("Expected no anomalies during a slow dilution.")
+	}
+
+	// A sudden drop and a diverging instant reading.
+	now = now.Add(time.Hour)
+	values = poll(2800, 2000)
+	if valueOf(t, values, "omnilogic_chlorinator_salt_anomaly", "anomaly=sudden_drop") != 1 {
+		t.Fatal("Expected a sudden drop.")
+	}
+	if valueOf(t, values, "omnilogic_chlorinator_salt_anomaly", "anomaly=divergence") != 1 {
+		t.Fatal("Expected diverging salt readings.")
+	}
+
+	// The drop clears once it leaves the sudden drop window.
+	now = now.Add(saltSuddenDropWindow + time.Minute)
+	values = poll(2800, 2800)
+	if valueOf(t, values, "omnilogic_chlorinator_salt_anomaly", "anomaly=sudden_drop") != 0 ||
+		valueOf(t, values, "omnilogic_chlorinator_salt_anomaly", "anomaly=divergence") != 0 {
+		t.Fatal("Expected the anomalies to clear.")
+	}
+
+	// Chlorinators without a salt reading are skipped.
+	values = poll(0, 0)
+	if len(values) != 0 {
+		t.Fatalf("Expected no metrics without a salt reading but found %v", len(values))
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	scheduleInfo = prometheus.NewDesc(prometheus.BuildFQName(namespace, "schedule", "info"),
+		"OmniLogic schedule from the MSP configuration. Times are in controller local time.",
+		[]string{"msp_system_id", "schedule_system_id", "bow_system_id", "equipment_id", "event", "start_time", "end_time", "days_active", "recurring"}, nil)
+	scheduleEnabled = prometheus.NewDesc(prometheus.BuildFQName(namespace, "schedule", "enabled"),
+		"Whether the schedule is enabled.", []string{"msp_system_id", "schedule_system_id", "equipment_id"}, nil)
+	scheduleData = prometheus.NewDesc(prometheus.BuildFQName(namespace, "schedule", "data"),
+		"Data the schedule applies to its equipment, e.g. the pump speed.", []string{"msp_system_id", "schedule_system_id", "equipment_id"}, nil)
+	scheduleActive = prometheus.NewDesc(prometheus.BuildFQName(namespace, "schedule", "active"),
+		"Whether the schedule is enabled and currently within its window, in controller local time.", []string{"msp_system_id", "schedule_system_id", "equipment_id"}, nil)
+	scheduleNextStart = prometheus.NewDesc(prometheus.BuildFQName(namespace, "schedule", "next_start_timestamp_seconds"),
+		"When the enabled schedule next starts, as seconds since the epoch.", []string{"msp_system_id", "schedule_system_id", "equipment_id"}, nil)
+)
+
+// Days of the week in the days-active bitmask, starting with Monday.
+var scheduleDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+// runsOn reports whether the schedule starts on the given day of the week.
+func (s Schedule) runsOn(day time.Weekday) bool {
+	for bit, scheduleDay := range scheduleDays {
+		if scheduleDay == day {
+			return s.DaysActive&(1<<uint(bit)) != 0
+		}
+	}
+	return false
+}
+
+// startOn returns the start of the schedule window on the day of t.
+func (s Schedule) startOn(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), s.StartHour, s.StartMinute, 0, 0, t.Location())
+}
+
+// duration returns the length of the schedule window. Windows ending at or
+// before their start time run past midnight.
+func (s Schedule) duration() time.Duration {
+	start := time.Duration(s.StartHour)*time.Hour + time.Duration(s.StartMinute)*time.Minute
+	end := time.Duration(s.EndHour)*time.Hour + time.Duration(s.EndMinute)*time.Minute
+	if end <= start {
+		end += 24 * time.Hour
+	}
+	return end - start
+}
+
+// activeAt reports whether the schedule is enabled and t, in controller local
+// time, falls within one of its windows.
+func (s Schedule) activeAt(t time.Time) bool {
+	if s.Enabled == 0 {
+		return false
+	}
+
+	// A window that runs past midnight may have started the day before.
+	for _, day := range []time.Time{t, t.AddDate(0, 0, -1)} {
+		if !s.runsOn(day.Weekday()) {
+			continue
+		}
+		start := s.startOn(day)
+		if !t.Before(start) && t.Before(start.Add(s.duration())) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextStart returns the first start of the enabled schedule after t.
+func (s Schedule) nextStart(t time.Time) (time.Time, bool) {
+	if s.Enabled == 0 {
+		return time.Time{}, false
+	}
+
+	for days := 0; days <= 7; days++ {
+		day := t.AddDate(0, 0, days)
+		start := s.startOn(day)
+		if s.runsOn(day.Weekday()) && start.After(t) {
+			return start, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// buildScheduleMetrics exports the schedules of a site's MSP configuration.
+func (e *Exporter) buildScheduleMetrics(ch chan<- prometheus.Metric, mspSystemId string, config *MspConfig) {
+	now := e.now().In(e.controllerLocation)
+
+	for _, schedule := range config.Schedules {
+		ch <- prometheus.MustNewConstMetric(scheduleInfo, prometheus.GaugeValue, 1, mspSystemId, schedule.ScheduleSystemID,
+			schedule.BowSystemID, schedule.EquipmentID, schedule.Event,
+			fmt.Sprintf("%02d:%02d", schedule.StartHour, schedule.StartMinute),
+			fmt.Sprintf("%02d:%02d", schedule.EndHour, schedule.EndMinute),
+			strconv.Itoa(schedule.DaysActive), strconv.Itoa(schedule.Recurring))
+
+		ch <- prometheus.MustNewConstMetric(scheduleEnabled, prometheus.GaugeValue, float64(schedule.Enabled), mspSystemId, schedule.ScheduleSystemID, schedule.EquipmentID)
+		ch <- prometheus.MustNewConstMetric(scheduleData, prometheus.GaugeValue, float64(schedule.Data), mspSystemId, schedule.ScheduleSystemID, schedule.EquipmentID)
+
+		active := 0.0
+		if schedule.activeAt(now) {
+			active = 1
+		}
+		ch <- prometheus.MustNewConstMetric(scheduleActive, prometheus.GaugeValue, active, mspSystemId, schedule.ScheduleSystemID, schedule.EquipmentID)
+
+		if next, ok := schedule.nextStart(now); ok {
+			ch <- prometheus.MustNewConstMetric(scheduleNextStart, prometheus.GaugeValue, float64(next.Unix()), mspSystemId, schedule.ScheduleSystemID, schedule.EquipmentID)
+		}
+	}
+}
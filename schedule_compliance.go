@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	scheduleCompliance = prometheus.NewDesc(prometheus.BuildFQName(namespace, "schedule", "compliance"),
+		"1 when scheduled equipment is in the state its active schedule demands, including the scheduled speed of filters and pumps, 0 otherwise.",
+		[]string{"msp_system_id", "equipment_id", "element"}, nil)
+)
+
+// scheduledStateAttributes maps the telemetry elements that schedules can
+// switch on to the attribute holding their state.
+var scheduledStateAttributes = map[string]string{
+	"filter": "filter_state",
+	"pump":   "pump_state",
+	"relay":  "relay_state",
+}
+
+// scheduledSpeedAttributes maps the telemetry elements whose schedules set a
+// speed in their Data to the attribute holding their speed. Both are in
+// percent.
+var scheduledSpeedAttributes = map[string]string{
+	"filter": "filter_speed",
+	"pump":   "pump_speed",
+}
+
+// buildScheduleComplianceMetrics compares the state of scheduled equipment in
+// the telemetry with what its schedules demand. Equipment must be on while
+// one of its schedules is active, and filters and pumps must run at the speed
+// of one of their active schedules; outside of its schedules it may be either.
+func (e *Exporter) buildScheduleComplianceMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	config, ok := e.mspConfigs[mspSystemId]
+	if !ok {
+		return
+	}
+
+	now := e.now().In(e.controllerLocation)
+
+	for _, item := range telemetryDataResponse.items() {
+		stateAttribute, ok := scheduledStateAttributes[item.name]
+		if !ok {
+			continue
+		}
+
+		state, err := strconv.ParseFloat(item.attributes[stateAttribute], 64)
+		if err != nil {
+			continue
+		}
+
+		scheduled, active := false, false
+		var speeds []float64
+		for _, schedule := range config.Schedules {
+			if schedule.EquipmentID != item.systemId || schedule.Enabled == 0 {
+				continue
+			}
+			scheduled = true
+			if schedule.activeAt(now) {
+				active = true
+				if schedule.Data > 0 {
+					speeds = append(speeds, float64(schedule.Data))
+				}
+			}
+		}
+
+		if !scheduled {
+			continue
+		}
+
+		compliant := !active || state != 0
+		if speedAttribute, ok := scheduledSpeedAttributes[item.name]; ok && compliant && len(speeds) > 0 {
+			if speed, err := strconv.ParseFloat(item.attributes[speedAttribute], 64); err == nil {
+				compliant = false
+				for _, scheduledSpeed := range speeds {
+					if speed == scheduledSpeed {
+						compliant = true
+					}
+				}
+			}
+		}
+
+		compliance := 1.0
+		if !compliant {
+			compliance = 0
+			e.scheduleMismatches.WithLabelValues(mspSystemId, item.systemId).Inc()
+		}
+
+		ch <- prometheus.MustNewConstMetric(scheduleCompliance, prometheus.GaugeValue, compliance, mspSystemId, item.systemId, item.name)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestScheduleComplianceMetrics(t *testing.T) {
+	telemetryData, err := parseTelemetryDataResponse(string(readFixture(t, "get_telemetry_data_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.controllerLocation = time.UTC
+	// Monday, April 4th 2022 at noon.
+	exporter.now = func() time.Time { return time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC) }
+	exporter.mspConfigs["54321"] = &MspConfig{
+		Schedules: []Schedule{
+			// Filter pump is on at 71%, as scheduled.
+			{EquipmentID: "2", ScheduleSystemID: "15", Enabled: 1, StartHour: 8, EndHour: 16, DaysActive: 127, Data: 71},
+			// Fountain relay is off, but should be on.
+			{EquipmentID: "5", ScheduleSystemID: "16", Enabled: 1, StartHour: 11, EndHour: 13, DaysActive: 127},
+			// Bubblers relay is off, and its only schedule is disabled.
+			{EquipmentID: "24", ScheduleSystemID: "17", Enabled: 0, StartHour: 11, EndHour: 13, DaysActive: 127},
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 10)
+		exporter.buildScheduleComplianceMetrics(ch, "54321", *telemetryData)
+		values := collectValues(t, ch)
+
+		if valueOf(t, values, "omnilogic_schedule_compliance", "equipment_id=2", "element=filter") != 1 {
+			t.Fatal("Expected filter pump to comply with its schedule.")
+		}
+
+		if valueOf(t, values, "omnilogic_schedule_compliance", "equipment_id=5", "element=relay") != 0 {
+			t.Fatal("Expected fountain relay not to comply with its schedule.")
+		}
+
+		if len(values) != 2 {
+			t.Fatalf("Expected compliance for 2 pieces of equipment but found %v", len(values))
+		}
+	}
+
+	if value := testutil.ToFloat64(exporter.scheduleMismatches.WithLabelValues("54321", "5")); value != 2 {
+		t.Fatalf("Expected 2 mismatched polls but found %v", value)
+	}
+
+	// The filter pump runs, but not at the scheduled speed.
+	exporter.mspConfigs["54321"].Schedules[0].Data = 100
+
+	ch := make(chan prometheus.Metric, 10)
+	exporter.buildScheduleComplianceMetrics(ch, "54321", *telemetryData)
+	values := collectValues(t, ch)
+
+	if valueOf(t, values, "omnilogic_schedule_compliance", "equipment_id=2", "element=filter") != 0 {
+		t.Fatal("Expected filter pump not to comply with the scheduled speed.")
+	}
+	if value := testutil.ToFloat64(exporter.scheduleMismatches.WithLabelValues("54321", "2")); value != 1 {
+		t.Fatalf("Expected 1 mismatched poll of the filter pump but found %v", value)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestScheduleActiveAt(t *testing.T) {
+	// Wednesday, 11am until 7pm.
+	wednesday := Schedule{Enabled: 1, StartHour: 11, EndHour: 19, DaysActive: 4}
+	// Every day, 10pm until 2am.
+	overnight := Schedule{Enabled: 1, StartHour: 22, EndHour: 2, DaysActive: 127}
+	// Saturday only, 10pm until 2am.
+	saturdayNight := Schedule{Enabled: 1, StartHour: 22, EndHour: 2, DaysActive: 32}
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		at       time.Time
+		expected bool
+	}{
+		{"within window", wednesday, time.Date(2022, 4, 6, 12, 0, 0, 0, time.UTC), true},
+		{"at start", wednesday, time.Date(2022, 4, 6, 11, 0, 0, 0, time.UTC), true},
+		{"at end", wednesday, time.Date(2022, 4, 6, 19, 0, 0, 0, time.UTC), false},
+		{"wrong day", wednesday, time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC), false},
+		{"disabled", Schedule{StartHour: 11, EndHour: 19, DaysActive: 127}, time.Date(2022, 4, 6, 12, 0, 0, 0, time.UTC), false},
+		{"before midnight", overnight, time.Date(2022, 4, 6, 23, 0, 0, 0, time.UTC), true},
+		{"after midnight", overnight, time.Date(2022, 4, 7, 1, 0, 0, 0, time.UTC), true},
+		{"after midnight from previous day", saturdayNight, time.Date(2022, 4, 10, 1, 0, 0, 0, time.UTC), true},
+		{"after midnight without previous day", saturdayNight, time.Date(2022, 4, 9, 1, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, test := range tests {
+		if actual := test.schedule.activeAt(test.at); actual != test.expected {
+			t.Fatalf("%v: expected %v but found %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestScheduleNextStart(t *testing.T) {
+	wednesday := Schedule{Enabled: 1, StartHour: 11, EndHour: 19, DaysActive: 4}
+
+	// Monday, April 4th 2022.
+	next, ok := wednesday.nextStart(time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC))
+	if !ok || !next.Equal(time.Date(2022, 4, 6, 11, 0, 0, 0, time.UTC)) {
+		t.Fatal("Expected next start on Wednesday.", next)
+	}
+
+	// Wednesday after the start wraps to the next week.
+	next, ok = wednesday.nextStart(time.Date(2022, 4, 6, 12, 0, 0, 0, time.UTC))
+	if !ok || !next.Equal(time.Date(2022, 4, 13, 11, 0, 0, 0, time.UTC)) {
+		t.Fatal("Expected next start on the following Wednesday.", next)
+	}
+
+	if _, ok := (Schedule{DaysActive: 127}).nextStart(time.Now()); ok {
+		t.Fatal("Expected no next start for a disabled schedule.")
+	}
+}
+
+func TestScheduleMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	if len(config.Schedules) != 4 {
+		t.Fatalf("Expected 4 schedules but found %v", len(config.Schedules))
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.controllerLocation = time.FixedZone("EDT", -4*60*60)
+	// Monday, April 4th 2022 at 8pm EDT.
+	exporter.now = func() time.Time { return time.Date(2022, 4, 5, 0, 0, 0, 0, time.UTC) }
+
+	ch := make(chan prometheus.Metric, 100)
+	exporter.buildScheduleMetrics(ch, "54321", config)
+	values := collectValues(t, ch)
+
+	if valueOf(t, values, "omnilogic_schedule_active", "schedule_system_id=19") != 1 {
+		t.Fatal("Expected light schedule 19 to be active.")
+	}
+
+	if valueOf(t, values, "omnilogic_schedule_active", "schedule_system_id=15") != 0 {
+		t.Fatal("Expected disabled schedule 15 to be inactive.")
+	}
+
+	if valueOf(t, values, "omnilogic_schedule_data", "schedule_system_id=18", "equipment_id=2") != 100 {
+		t.Fatal("Expected schedule 18 to run equipment 2 at 100.")
+	}
+
+	// Tuesday at 7:30pm EDT.
+	expectedNext := float64(time.Date(2022, 4, 5, 23, 30, 0, 0, time.UTC).Unix())
+	if next := valueOf(t, values, "omnilogic_schedule_next_start_timestamp_seconds", "schedule_system_id=19"); next != expectedNext {
+		t.Fatalf("Expected schedule 19 to start next at %v but found %v", expectedNext, next)
+	}
+
+	if valueOf(t, values, "omnilogic_schedule_info", "schedule_system_id=19", "start_time=19:30", "end_time=22:30") != 1 {
+		t.Fatal("Expected schedule 19 info.")
+	}
+}
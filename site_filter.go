@@ -0,0 +1,96 @@
+package main
+
+import (
+	"regexp"
+)
+
+// SiteFilter decides which OmniLogic sites are exported. Sites can be
+// selected by exact MspSystemID or by a regular expression on BackyardName.
+// When any include rule is configured a site must match one of them, and a
+// matching exclude rule always wins.
+type SiteFilter struct {
+	includeIDs   map[string]bool
+	excludeIDs   map[string]bool
+	includeNames []*regexp.Regexp
+	excludeNames []*regexp.Regexp
+}
+
+// NewSiteFilter returns a SiteFilter for the given MspSystemIDs and
+// BackyardName regular expressions. The regular expressions are anchored.
+func NewSiteFilter(includeIDs, excludeIDs, includeNames, excludeNames []string) (*SiteFilter, error) {
+	f := &SiteFilter{
+		includeIDs: stringSet(includeIDs),
+		excludeIDs: stringSet(excludeIDs),
+	}
+
+	var err error
+
+	if f.includeNames, err = compileAnchored(includeNames); err != nil {
+		return nil, err
+	}
+
+	if f.excludeNames, err = compileAnchored(excludeNames); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Apply splits sites into those that should be exported and those that were
+// filtered out.
+func (f *SiteFilter) Apply(sites []*Site) (kept []*Site, filtered []*Site) {
+	for _, site := range sites {
+		if f.Matches(site) {
+			kept = append(kept, site)
+		} else {
+			filtered = append(filtered, site)
+		}
+	}
+	return kept, filtered
+}
+
+// Matches reports whether site should be exported.
+func (f *SiteFilter) Matches(site *Site) bool {
+	if f == nil {
+		return true
+	}
+
+	if f.excludeIDs[site.MspSystemID] || matchesAny(f.excludeNames, site.BackyardName) {
+		return false
+	}
+
+	if len(f.includeIDs) == 0 && len(f.includeNames) == 0 {
+		return true
+	}
+
+	return f.includeIDs[site.MspSystemID] || matchesAny(f.includeNames, site.BackyardName)
+}
+
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+func compileAnchored(expressions []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, expression := range expressions {
+		re, err := regexp.Compile("^(?:" + expression + ")$")
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func matchesAny(expressions []*regexp.Regexp, value string) bool {
+	for _, re := range expressions {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSiteFilter(t *testing.T) {
+	home := &Site{MspSystemID: "54321", BackyardName: "Home"}
+	beach := &Site{MspSystemID: "98765", BackyardName: "Beach"}
+	demo := &Site{MspSystemID: "11111", BackyardName: "Demo Backyard"}
+	sites := []*Site{home, beach, demo}
+
+	tests := []struct {
+		name                                               string
+		includeIDs, excludeIDs, includeNames, excludeNames []string
+		expected                                           []*Site
+	}{
+		{name: "no filters", expected: []*Site{home, beach, demo}},
+		{name: "exclude id", excludeIDs: []string{"98765"}, expected: []*Site{home, demo}},
+		{name: "exclude name", excludeNames: []string{"Demo.*"}, expected: []*Site{home, beach}},
+		{name: "include id", includeIDs: []string{"54321"}, expected: []*Site{home}},
+		{name: "include name", includeNames: []string{"Home|Beach"}, expected: []*Site{home, beach}},
+		{name: "name is anchored", includeNames: []string{"Back"}, expected: nil},
+		{name: "exclude wins", includeIDs: []string{"54321", "98765"}, excludeNames: []string{"Beach"}, expected: []*Site{home}},
+	}
+
+	for _, test := range tests {
+		filter, err := NewSiteFilter(test.includeIDs, test.excludeIDs, test.includeNames, test.excludeNames)
+		if err != nil {
+			t.Fatalf("%v: error creating filter: %v", test.name, err)
+		}
+
+		kept, filtered := filter.Apply(sites)
+
+		if len(kept) != len(test.expected) {
+			t.Fatalf("%v: expected %v sites but found %v", test.name, len(test.expected), len(kept))
+		}
+		for i := range kept {
+			if kept[i] != test.expected[i] {
+				t.Fatalf("%v: expected site %v but found %v", test.name, test.expected[i], kept[i])
+			}
+		}
+		if len(kept)+len(filtered) != len(sites) {
+			t.Fatalf("%v: sites were lost while filtering", test.name)
+		}
+	}
+}
+
+func TestSiteFilterInvalidRegex(t *testing.T) {
+	if _, err := NewSiteFilter(nil, nil, []string{"("}, nil); err == nil {
+		t.Fatal("Expected an error for an invalid regular expression.")
+	}
+}
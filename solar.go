@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const solarHeaterType = "HTR_SOLAR"
+
+var (
+	solarHeaterState = prometheus.NewDesc(prometheus.BuildFQName(namespace, "solar", "heater_state"),
+		"State of the solar heater, 1 for the current state.", []string{"msp_system_id", "system_id", "name", "state"}, nil)
+	solarTemperature = prometheus.NewDesc(prometheus.BuildFQName(namespace, "solar", "temperature_degrees"),
+		"Temperature at the solar sensor of the solar heater.", []string{"msp_system_id", "system_id", "name", "unit"}, nil)
+	solarSetpoint = prometheus.NewDesc(prometheus.BuildFQName(namespace, "solar", "setpoint_degrees"),
+		"Solar set point of the virtual heater operating the solar heater.", []string{"msp_system_id", "system_id", "unit"}, nil)
+
+	// heaterStates maps the heaterState telemetry of heaters to names,
+	// following the HeaterState enum of python-omnilogic-local
+	// (pyomnilogic_local/omnitypes.py).
+	heaterStates = []struct {
+		value string
+		name  string
+	}{
+		{"0", "off"},
+		{"1", "heating"},
+		{"2", "paused"},
+	}
+)
+
+// solarTemperatureUnit returns "fahrenheit" or "celsius" for the solar sensor
+// of the site.
+func (c *MspConfig) solarTemperatureUnit() string {
+	sensors := c.Backyard.Sensors
+	for _, bow := range c.Backyard.BodiesOfWater {
+		sensors = append(sensors, bow.Sensors...)
+	}
+	return c.temperatureUnit(sensors, "SENSOR_SOLAR_TEMP")
+}
+
+// buildSolarMetrics exports the state and solar sensor temperature of each
+// solar heater, and the solar set point of the virtual heaters operating
+// them. Heaters report a negative temperature without a solar sensor.
+func (e *Exporter) buildSolarMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	config, ok := e.mspConfigs[mspSystemId]
+	if !ok {
+		return
+	}
+	unit := config.solarTemperatureUnit()
+
+	items := map[string]TelemetryDataItem{}
+	for _, item := range telemetryDataResponse.items() {
+		items[item.name+"/"+item.systemId] = item
+	}
+
+	for _, bow := range config.Backyard.BodiesOfWater {
+		for _, heater := range bow.Heaters {
+			hasSolar := false
+			for _, equipment := range heater.Equipment {
+				if equipment.HeaterType != solarHeaterType {
+					continue
+				}
+				hasSolar = true
+
+				item, ok := items["heater/"+equipment.SystemID]
+				if !ok {
+					continue
+				}
+
+				if state, ok := item.attributes["heater_state"]; ok {
+					for _, known := range heaterStates {
+						value := 0.0
+						if known.value == state {
+							value = 1
+						}
+						ch <- prometheus.MustNewConstMetric(solarHeaterState, prometheus.GaugeValue, value, mspSystemId, equipment.SystemID, equipment.Name, known.name)
+					}
+				}
+
+				if temperature, err := strconv.ParseFloat(item.attributes["temp"], 64); err == nil && temperature >= 0 {
+					ch <- prometheus.MustNewConstMetric(solarTemperature, prometheus.GaugeValue, temperature, mspSystemId, equipment.SystemID, equipment.Name, unit)
+				}
+			}
+
+			if !hasSolar {
+				continue
+			}
+			if setpoint, err := strconv.ParseFloat(items["virtual_heater/"+heater.SystemID].attributes["solar_set_point"], 64); err == nil {
+				ch <- prometheus.MustNewConstMetric(solarSetpoint, prometheus.GaugeValue, setpoint, mspSystemId, heater.SystemID, unit)
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSolarMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	if heaterType := config.Backyard.BodiesOfWater[0].Heaters[0].Equipment[0].HeaterType; heaterType != "HTR_HEAT_PUMP" {
+		t.Fatalf("Expected a heat pump but found %v", heaterType)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.mspConfigs["54321"] = config
+
+	telemetryData, err := parseTelemetryDataResponse(`<STATUS version="1.0">
+    <Backyard systemId="54321" statusVersion="8" />
+    <VirtualHeater systemId="22" Current-Set-Point="90" SolarSetPoint="95" enable="yes" />
+    <Heater systemId="23" heaterState="1" temp="-1" enable="yes" />
+    <Heater systemId="25" heaterState="2" temp="31" enable="yes" />
+</STATUS>`)
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	poll := func() map[string]float64 {
+		ch := make(chan prometheus.Metric, 10)
+		exporter.buildSolarMetrics(ch, "54321", *telemetryData)
+		return collectValues(t, ch)
+	}
+
+	if values := poll(); len(values) != 0 {
+		t.Fatalf("Expected no solar metrics without a solar heater but found %v", len(values))
+	}
+
+	bow := &config.Backyard.BodiesOfWater[0]
+	bow.Heaters[0].Equipment = append(bow.Heaters[0].Equipment, MspHeaterEquipment{
+		MspEquipment: MspEquipment{SystemID: "25", Name: "Solar", Type: "PET_HEATER"},
+		HeaterType:   "HTR_SOLAR",
+	})
+	bow.Sensors = append(bow.Sensors, MspEquipment{SystemID: "26", Name: "SolarSensor", Type: "SENSOR_SOLAR_TEMP", Units: "UNITS_CELSIUS"})
+
+	values := poll()
+
+	if valueOf(t, values, "omnilogic_solar_heater_state", "system_id=25", "name=Solar", "state=paused") != 1 ||
+		valueOf(t, values, "omnilogic_solar_heater_state", "system_id=25", "state=heating") != 0 {
+		t.Fatal("Expected the solar heater to be paused.")
+	}
+	if valueOf(t, values, "omnilogic_solar_temperature_degrees", "system_id=25", "unit=celsius") != 31 {
+		t.Fatal("Expected a solar temperature of 31C.")
+	}
+	if valueOf(t, values, "omnilogic_solar_setpoint_degrees", "system_id=22", "unit=celsius") != 95 {
+		t.Fatal("Expected a solar set point of 95.")
+	}
+	if len(values) != len(heaterStates)+2 {
+		t.Fatalf("Expected only the solar heater to be exported but found %v metrics", len(values))
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const spilloverValvePosition = "3"
+
+var (
+	bodyOfWaterSharingInfo = prometheus.NewDesc(prometheus.BuildFQName(namespace, "body_of_water", "sharing_info"),
+		"How the body of water shares equipment with another body of water.",
+		[]string{"msp_system_id", "system_id", "name", "shared_type", "shared_priority", "shared_equipment_system_id"}, nil)
+	bodyOfWaterSupportsSpillover = prometheus.NewDesc(prometheus.BuildFQName(namespace, "body_of_water", "supports_spillover"),
+		"Whether the body of water supports spilling over into another body of water.", []string{"msp_system_id", "system_id", "name"}, nil)
+	bodyOfWaterSpillover = prometheus.NewDesc(prometheus.BuildFQName(namespace, "body_of_water", "spillover_active"),
+		"Whether a filter of the body of water has its valves in the spillover position.", []string{"msp_system_id", "system_id", "name"}, nil)
+	filterValvePosition = prometheus.NewDesc(prometheus.BuildFQName(namespace, "filter", "valve_position_state"),
+		"Valve position of the filter, 1 for the current position.", []string{"msp_system_id", "system_id", "name", "position"}, nil)
+
+	// filterValvePositions maps the valvePosition telemetry of filters to
+	// names, following the FilterValvePosition enum of python-omnilogic-local
+	// (pyomnilogic_local/omnitypes.py).
+	filterValvePositions = []struct {
+		value string
+		name  string
+	}{
+		{"1", "pool_only"},
+		{"2", "spa_only"},
+		{spilloverValvePosition, "spillover"},
+		{"4", "low_priority_heat"},
+		{"5", "high_priority_heat"},
+	}
+)
+
+// buildSpilloverMetrics exports how bodies of water share equipment, the
+// valve positions of their filters and whether they are spilling over.
+func (e *Exporter) buildSpilloverMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	config, ok := e.mspConfigs[mspSystemId]
+	if !ok {
+		return
+	}
+
+	valvePositions := map[string]string{}
+	for _, item := range telemetryDataResponse.itemsNamed("filter") {
+		if position, ok := item.attributes["valve_position"]; ok {
+			valvePositions[item.systemId] = position
+		}
+	}
+
+	for _, bow := range config.Backyard.BodiesOfWater {
+		ch <- prometheus.MustNewConstMetric(bodyOfWaterSharingInfo, prometheus.GaugeValue, 1,
+			mspSystemId, bow.SystemID, bow.Name, bow.SharedType, bow.SharedPriority, bow.SharedEquipmentSystemID)
+
+		supportsSpillover := 0.0
+		if bow.SupportsSpillover == "yes" {
+			supportsSpillover = 1
+		}
+		ch <- prometheus.MustNewConstMetric(bodyOfWaterSupportsSpillover, prometheus.GaugeValue, supportsSpillover, mspSystemId, bow.SystemID, bow.Name)
+
+		spillover, haveFilter := 0.0, false
+		for _, filter := range bow.Filters {
+			position, ok := valvePositions[filter.SystemID]
+			if !ok {
+				continue
+			}
+			haveFilter = true
+
+			if position == spilloverValvePosition {
+				spillover = 1
+			}
+
+			for _, known := range filterValvePositions {
+				value := 0.0
+				if known.value == position {
+					value = 1
+				}
+				ch <- prometheus.MustNewConstMetric(filterValvePosition, prometheus.GaugeValue, value, mspSystemId, filter.SystemID, filter.Name, known.name)
+			}
+		}
+
+		if haveFilter {
+			ch <- prometheus.MustNewConstMetric(bodyOfWaterSpillover, prometheus.GaugeValue, spillover, mspSystemId, bow.SystemID, bow.Name)
+		}
+	}
+}
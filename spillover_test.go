@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSpilloverMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	exporter.mspConfigs["54321"] = config
+
+	poll := func(valvePosition int) map[string]float64 {
+		telemetryData, err := parseTelemetryDataResponse(fmt.Sprintf(`<STATUS version="1.0">
+    <Backyard systemId="54321" statusVersion="8" />
+    <Filter systemId="2" valvePosition="%d" filterSpeed="71" filterState="1" lastSpeed="71" />
+</STATUS>`, valvePosition))
+		if err != nil {
+			t.Fatal("Error parsing telemetry data response.", err)
+		}
+		ch := make(chan prometheus.Metric, 10)
+		exporter.buildSpilloverMetrics(ch, "54321", *telemetryData)
+		return collectValues(t, ch)
+	}
+
+	values := poll(1)
+
+	if valueOf(t, values, "omnilogic_body_of_water_sharing_info", "system_id=1", "name=Pool",
+		"shared_type=BOW_NO_EQUIPMENT_SHARED", "shared_priority=SHARED_EQUIPMENT_LOW_PRIORITY", "shared_equipment_system_id=0") != 1 {
+		t.Fatal("Expected the sharing info of the pool.")
+	}
+	if valueOf(t, values, "omnilogic_body_of_water_supports_spillover", "system_id=1") != 0 {
+		t.Fatal("Expected the pool not to support spillover.")
+	}
+	if valueOf(t, values, "omnilogic_filter_valve_position_state", "system_id=2", "name=Filter Pump", "position=pool_only") != 1 {
+		t.Fatal("Expected the filter valves in the pool only position.")
+	}
+	if valueOf(t, values, "omnilogic_body_of_water_spillover_active", "system_id=1") != 0 {
+		t.Fatal("Expected no spillover.")
+	}
+
+	values = poll(3)
+	if valueOf(t, values, "omnilogic_filter_valve_position_state", "system_id=2", "position=spillover") != 1 ||
+		valueOf(t, values, "omnilogic_filter_valve_position_state", "system_id=2", "position=pool_only") != 0 {
+		t.Fatal("Expected the filter valves in the spillover position.")
+	}
+	if valueOf(t, values, "omnilogic_body_of_water_spillover_active", "system_id=1") != 1 {
+		t.Fatal("Expected spillover.")
+	}
+}
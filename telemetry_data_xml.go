@@ -14,13 +14,16 @@ var (
 	gaugeMetrics = map[string]prometheus.Gauge{}
 )
 
-func getGaugeMetric(namespace string, subsystem string, name string, mspSystemId, itemSystemId string) prometheus.Gauge {
-	key := prometheus.BuildFQName(namespace, subsystem, name) + "_" + itemSystemId
+func getGaugeMetric(namespace string, subsystem string, name string, mspSystemId string, item TelemetryDataItem) prometheus.Gauge {
+	key := prometheus.BuildFQName(namespace, subsystem, name) + "_" + mspSystemId + "_" + item.parentSystemId + "_" + item.systemId
 	gauge, exists := gaugeMetrics[key]
 	if !exists {
 		labels := map[string]string{}
-		if len(itemSystemId) > 0 {
-			labels["system_id"] = itemSystemId
+		if len(item.systemId) > 0 {
+			labels["system_id"] = item.systemId
+		}
+		if len(item.parentSystemId) > 0 {
+			labels["parent_system_id"] = item.parentSystemId
 		}
 		if len(mspSystemId) > 0 {
 			labels["msp_system_id"] = mspSystemId
@@ -40,6 +43,7 @@ func getGaugeMetric(namespace string, subsystem string, name string, mspSystemId
 
 type Status struct {
 	XMLName   xml.Name            `xml:"STATUS"`
+	Version   string              `xml:"version,attr"`
 	DataItems []TelemetryDataItem `xml:",any"`
 }
 
@@ -47,12 +51,26 @@ type TelemetryDataItem struct {
 	name       string
 	systemId   string
 	attributes map[string]string
+
+	// path is the slash separated snake case names of the enclosing
+	// elements below STATUS, and parentSystemId the systemId of the nearest
+	// enclosing element that has one. Both are empty for top level elements.
+	path           string
+	parentSystemId string
+	children       []TelemetryDataItem
 }
 
 func (i *TelemetryDataItem) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return i.unmarshalNested(d, start, "", "")
+}
+
+// unmarshalNested decodes an element and, recursively, its child elements.
+func (i *TelemetryDataItem) unmarshalNested(d *xml.Decoder, start xml.StartElement, path string, parentSystemId string) error {
 	i.attributes = make(map[string]string)
 
 	i.name = strcase.ToSnake(start.Name.Local)
+	i.path = path
+	i.parentSystemId = parentSystemId
 
 	for _, attr := range start.Attr {
 		if attr.Name.Local == "systemId" {
@@ -64,10 +82,66 @@ func (i *TelemetryDataItem) UnmarshalXML(d *xml.Decoder, start xml.StartElement)
 		}
 	}
 
-	// Signal we're done parsing this element.
-	d.Skip()
+	childPath := i.name
+	if len(path) > 0 {
+		childPath = path + "/" + i.name
+	}
+	childParentSystemId := parentSystemId
+	if len(i.systemId) > 0 {
+		childParentSystemId = i.systemId
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
 
-	return nil
+		switch t := token.(type) {
+		case xml.StartElement:
+			var child TelemetryDataItem
+			if err := child.unmarshalNested(d, t, childPath, childParentSystemId); err != nil {
+				return err
+			}
+			i.children = append(i.children, child)
+		case xml.EndElement:
+			// Signal we're done parsing this element.
+			return nil
+		}
+	}
+}
+
+// items returns all telemetry items, including nested ones, depth first.
+// Equipment reported more than once, e.g. a heater below two virtual heaters
+// or an element at top level and nested, is only returned the first time.
+func (s Status) items() []TelemetryDataItem {
+	var items []TelemetryDataItem
+	seen := map[string]bool{}
+	var walk func([]TelemetryDataItem)
+	walk = func(siblings []TelemetryDataItem) {
+		for _, item := range siblings {
+			key := item.name + "/" + item.systemId
+			if len(item.systemId) == 0 || !seen[key] {
+				seen[key] = true
+				items = append(items, item)
+			}
+			walk(item.children)
+		}
+	}
+	walk(s.DataItems)
+	return items
+}
+
+// itemsNamed returns the telemetry items with the given snake case element
+// name, including nested ones.
+func (s Status) itemsNamed(name string) []TelemetryDataItem {
+	var items []TelemetryDataItem
+	for _, item := range s.items() {
+		if item.name == name {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 func parseTelemetryDataResponse(response string) (*Status, error) {
@@ -79,8 +153,8 @@ func parseTelemetryDataResponse(response string) (*Status, error) {
 	return &statusXml, nil
 }
 
-func buildMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) error {
-	items := telemetryDataResponse.DataItems
+func buildMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status, exclude func(item TelemetryDataItem, attribute string) bool) error {
+	items := telemetryDataResponse.items()
 
 	floatRegex, _ := regexp.Compile("^[+-]?([0-9]+([.][0-9]*)?|[.][0-9]+)$")
 
@@ -92,6 +166,10 @@ func buildMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryData
 	for _, item := range items {
 		for k, v := range item.attributes {
 
+			if exclude != nil && exclude(item, k) {
+				continue
+			}
+
 			// If it has a value, try and parse it.
 			if len(v) > 0 {
 				if floatRegex.MatchString(v) {
@@ -100,9 +178,9 @@ func buildMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryData
 					floatValue, err := strconv.ParseFloat(v, 64)
 					// A possibly poor assumption that negative values are invalid (e.g. airtemp)
 					if err == nil && floatValue >= 0 {
-						gaugeMetric := getGaugeMetric(namespace, item.name, k, mspSystemId, item.systemId)
+						gaugeMetric := getGaugeMetric(namespace, item.name, k, mspSystemId, item)
 						gaugeMetric.Set(floatValue)
-						metricMap[item.name+k+item.systemId] = gaugeMetric
+						metricMap[item.name+k+item.parentSystemId+item.systemId] = gaugeMetric
 					}
 				} else if yesNoRegex.MatchString(strings.ToLower(v)) {
 					// Matches yes or no, treat as a guage with a value of 1 or 0.
@@ -110,9 +188,9 @@ func buildMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryData
 					if strings.ToLower(v) == "yes" {
 						floatValue = 1.0
 					}
-					gaugeMetric := getGaugeMetric(namespace, item.name, k, mspSystemId, item.systemId)
+					gaugeMetric := getGaugeMetric(namespace, item.name, k, mspSystemId, item)
 					gaugeMetric.Set(floatValue)
-					metricMap[item.systemId] = gaugeMetric
+					metricMap[item.name+k+item.parentSystemId+item.systemId] = gaugeMetric
 				}
 			}
 
@@ -45,7 +45,7 @@ func TestTelemetryDataItem2(t *testing.T) {
 	}
 
 	metrics := make(chan prometheus.Metric, 100)
-	buildMetrics(metrics, "54321", *telemetryData)
+	buildMetrics(metrics, "54321", *telemetryData, nil)
 
 	// CSAD dupes should be removed
 	if len(metrics) != 56 {
@@ -53,3 +53,72 @@ func TestTelemetryDataItem2(t *testing.T) {
 	}
 
 }
+
+func TestNestedTelemetryDataItems(t *testing.T) {
+	telemetryData, err := parseTelemetryDataResponse(`<STATUS version="1.0">
+    <Backyard systemId="54321" statusVersion="8" airTemp="70" />
+    <VirtualHeater systemId="15" Current-Set-Point="85" enable="yes">
+        <Heater systemId="16" heaterState="1" />
+        <Operation>
+            <Heater systemId="17" heaterState="0" />
+        </Operation>
+    </VirtualHeater>
+</STATUS>`)
+
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	if len(telemetryData.DataItems) != 2 || len(telemetryData.items()) != 5 {
+		t.Fatalf("Expected 2 top level and 5 total data items but found %v and %v", len(telemetryData.DataItems), len(telemetryData.items()))
+	}
+
+	heaters := telemetryData.itemsNamed("heater")
+	if len(heaters) != 2 {
+		t.Fatalf("Expected 2 nested heaters but found %v", len(heaters))
+	}
+	if heaters[0].path != "virtual_heater" || heaters[0].parentSystemId != "15" {
+		t.Fatal("Expected heater 16 below virtual heater 15.", heaters[0])
+	}
+	if heaters[1].path != "virtual_heater/operation" || heaters[1].parentSystemId != "15" {
+		t.Fatal("Expected heater 17 below the operation of virtual heater 15.", heaters[1])
+	}
+
+	metrics := make(chan prometheus.Metric, 10)
+	buildMetrics(metrics, "54321", *telemetryData, nil)
+	values := collectValues(t, metrics)
+
+	if valueOf(t, values, "omnilogic_heater_heater_state", "parent_system_id=15", "system_id=17") != 0 ||
+		valueOf(t, values, "omnilogic_heater_heater_state", "parent_system_id=15", "system_id=16") != 1 {
+		t.Fatal("Expected heater states of the nested heaters with parent_system_id 15.")
+	}
+}
+
+func TestNestedYesNoTelemetry(t *testing.T) {
+	// The same heater operated by two virtual heaters.
+	telemetryData, err := parseTelemetryDataResponse(`<STATUS version="1.0">
+    <VirtualHeater systemId="15" enable="yes">
+        <Heater systemId="16" enable="yes" />
+    </VirtualHeater>
+    <VirtualHeater systemId="18" enable="no">
+        <Heater systemId="16" enable="no" />
+    </VirtualHeater>
+</STATUS>`)
+
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	metrics := make(chan prometheus.Metric, 10)
+	buildMetrics(metrics, "54321", *telemetryData, nil)
+	values := collectValues(t, metrics)
+
+	// Heater 16 is only exported below the first virtual heater.
+	if len(values) != 3 {
+		t.Fatalf("Expected 3 metrics but found %v", len(values))
+	}
+	if valueOf(t, values, "omnilogic_heater_enable", "parent_system_id=15", "system_id=16") != 1 ||
+		valueOf(t, values, "omnilogic_virtual_heater_enable", "system_id=18") != 0 {
+		t.Fatal("Expected the enable flags of heater 16 and both virtual heaters.")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	telemetryInfo = prometheus.NewDesc(prometheus.BuildFQName(namespace, "telemetry", "info"),
+		"Version of the telemetry STATUS document and the Backyard statusVersion.", []string{"msp_system_id", "version", "status_version"}, nil)
+	telemetrySchemaKnown = prometheus.NewDesc(prometheus.BuildFQName(namespace, "telemetry", "schema_known"),
+		"Whether the telemetry statusVersion is known to this exporter.", []string{"msp_system_id", "status_version"}, nil)
+)
+
+// telemetrySchema lists the known attributes of each telemetry element, using
+// the snake case names produced by TelemetryDataItem.
+type telemetrySchema map[string]map[string]bool
+
+func newTelemetrySchema(elements map[string][]string) telemetrySchema {
+	schema := telemetrySchema{}
+	for element, attributes := range elements {
+		schema[element] = stringSet(attributes)
+	}
+	return schema
+}
+
+var telemetrySchemaV8 = newTelemetrySchema(map[string][]string{
+	"backyard":          {"status_version", "air_temp", "status", "state", "config_updated_time", "datetime"},
+	"body_of_water":     {"flow", "water_temp"},
+	"filter":            {"valve_position", "filter_speed", "filter_state", "last_speed"},
+	"virtual_heater":    {"current_set_point", "enable", "solar_set_point"},
+	"heater":            {"heater_state", "enable", "temp"},
+	"chlorinator":       {"operating_mode", "timed_percent", "sc_mode", "chlr_error", "chlr_alert", "avg_salt_level", "instant_salt_level", "status"},
+	"pump":              {"pump_state", "pump_speed", "last_speed"},
+	"relay":             {"relay_state"},
+	"color_logic_light": {"light_state", "current_show", "speed", "brightness"},
+	"csad":              {"ph", "orp", "status", "mode"},
+	"group":             {"group_state"},
+})
+
+// telemetrySchemas maps each known Backyard statusVersion to its schema.
+var telemetrySchemas = map[string]telemetrySchema{
+	"8": telemetrySchemaV8,
+	"9": telemetrySchemaV8,
+}
+
+// latestTelemetrySchema is used to check telemetry with an unknown
+// statusVersion.
+var latestTelemetrySchema = telemetrySchemas["9"]
+
+// statusVersion returns the statusVersion attribute of the Backyard element.
+func (s Status) statusVersion() string {
+	for _, item := range s.itemsNamed("backyard") {
+		return item.attributes["status_version"]
+	}
+	return ""
+}
+
+// checkTelemetrySchema compares the telemetry against the schema registry,
+// counting unknown attributes and logging the first time each is seen.
+func (e *Exporter) checkTelemetrySchema(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	statusVersion := telemetryDataResponse.statusVersion()
+
+	ch <- prometheus.MustNewConstMetric(telemetryInfo, prometheus.GaugeValue, 1, mspSystemId, telemetryDataResponse.Version, statusVersion)
+
+	schema, known := telemetrySchemas[statusVersion]
+	if known {
+		ch <- prometheus.MustNewConstMetric(telemetrySchemaKnown, prometheus.GaugeValue, 1, mspSystemId, statusVersion)
+	} else {
+		ch <- prometheus.MustNewConstMetric(telemetrySchemaKnown, prometheus.GaugeValue, 0, mspSystemId, statusVersion)
+		schema = latestTelemetrySchema
+		if !e.schemaDriftReported["status_version:"+statusVersion] {
+			e.schemaDriftReported["status_version:"+statusVersion] = true
+			level.Warn(e.logger).Log("msg", "Unknown telemetry statusVersion, checking against the latest known schema.", "MspSystemID", mspSystemId, "statusVersion", statusVersion)
+		}
+	}
+
+	for _, item := range telemetryDataResponse.items() {
+		attributes, knownElement := schema[item.name]
+
+		if !knownElement && !e.schemaDriftReported[item.name] {
+			e.schemaDriftReported[item.name] = true
+			level.Warn(e.logger).Log("msg", "Unknown telemetry element.", "MspSystemID", mspSystemId, "statusVersion", statusVersion, "element", item.name)
+		}
+
+		for attribute := range item.attributes {
+			if attributes[attribute] {
+				continue
+			}
+
+			e.unknownAttributes.WithLabelValues(item.name, attribute).Inc()
+
+			if knownElement && !e.schemaDriftReported[item.name+"."+attribute] {
+				e.schemaDriftReported[item.name+"."+attribute] = true
+				level.Warn(e.logger).Log("msg", "Unknown telemetry attribute.", "MspSystemID", mspSystemId, "statusVersion", statusVersion, "element", item.name, "attribute", attribute)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTelemetrySchemaKnownFixtures(t *testing.T) {
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	for _, fixture := range []string{"get_telemetry_data_response.xml", "get_telemetry_data_response2.xml"} {
+		telemetryData, err := parseTelemetryDataResponse(string(readFixture(t, fixture)))
+
+		if err != nil {
+			t.Fatal("Error parsing telemetry data response.", err)
+		}
+
+		ch := make(chan prometheus.Metric, 10)
+		exporter.checkTelemetrySchema(ch, "54321", *telemetryData)
+		values := collectValues(t, ch)
+
+		if valueOf(t, values, "omnilogic_telemetry_schema_known", "msp_system_id=54321") != 1 {
+			t.Fatalf("Expected the statusVersion of %v to be known", fixture)
+		}
+	}
+
+	if count := testutil.CollectAndCount(exporter.unknownAttributes); count != 0 {
+		t.Fatalf("Expected no unknown attributes but found %v", count)
+	}
+}
+
+func TestTelemetrySchemaDrift(t *testing.T) {
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	telemetryData, err := parseTelemetryDataResponse(`<STATUS version="1.1">
+    <Backyard systemId="54321" statusVersion="10" airTemp="53" status="2" state="1" />
+    <Filter systemId="2" filterSpeed="71" filterState="1" whyFilterIsOn="14" />
+    <Heater systemId="23" heaterState="1" />
+    <Waterfall systemId="30" waterfallState="1" />
+</STATUS>`)
+
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 10)
+		exporter.checkTelemetrySchema(ch, "54321", *telemetryData)
+		values := collectValues(t, ch)
+
+		if valueOf(t, values, "omnilogic_telemetry_schema_known", "status_version=10") != 0 {
+			t.Fatal("Expected statusVersion 10 to be unknown")
+		}
+		if valueOf(t, values, "omnilogic_telemetry_info", "version=1.1", "status_version=10") != 1 {
+			t.Fatal("Expected telemetry info to carry the versions")
+		}
+	}
+
+	if value := testutil.ToFloat64(exporter.unknownAttributes.WithLabelValues("filter", "why_filter_is_on")); value != 2 {
+		t.Fatalf("Expected why_filter_is_on to be counted twice but found %v", value)
+	}
+
+	if value := testutil.ToFloat64(exporter.unknownAttributes.WithLabelValues("waterfall", "waterfall_state")); value != 2 {
+		t.Fatalf("Expected waterfall_state to be counted twice but found %v", value)
+	}
+
+	if count := testutil.CollectAndCount(exporter.unknownAttributes); count != 2 {
+		t.Fatalf("Expected two unknown attributes but found %v", count)
+	}
+}
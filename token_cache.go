@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// TokenCache persists the HAAPI session UserID and Token on disk so that a
+// restarted exporter can reuse them instead of logging in again.
+type TokenCache struct {
+	path string
+	key  []byte
+}
+
+type cachedToken struct {
+	UserID string `json:"user_id"`
+	Token  string `json:"token"`
+}
+
+// NewTokenCache returns a TokenCache backed by the file at path. If keyFile is
+// not empty, the cache is encrypted with AES-GCM using a key derived from the
+// contents of keyFile.
+func NewTokenCache(path string, keyFile string) (*TokenCache, error) {
+	cache := &TokenCache{path: path}
+
+	if len(keyFile) > 0 {
+		keyText, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		keyText = bytes.TrimSpace(keyText)
+		if len(keyText) == 0 {
+			return nil, errors.New("token cache key file is empty")
+		}
+		key := sha256.Sum256(keyText)
+		cache.key = key[:]
+	}
+
+	return cache, nil
+}
+
+// Load reads a previously stored session. It returns nil without an error
+// when no cache file exists yet.
+func (c *TokenCache) Load() (*Session, error) {
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.key != nil {
+		data, err = c.decrypt(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var token cachedToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, err
+	}
+
+	if len(token.UserID) == 0 || len(token.Token) == 0 {
+		return nil, errors.New("token cache is incomplete")
+	}
+
+	return &Session{
+		UserID: token.UserID,
+		Token:  token.Token,
+		Status: "0",
+	}, nil
+}
+
+// Store writes the session UserID and Token to the cache file with 0600
+// permissions.
+func (c *TokenCache) Store(session *Session) error {
+	data, err := json.Marshal(cachedToken{UserID: session.UserID, Token: session.Token})
+	if err != nil {
+		return err
+	}
+
+	if c.key != nil {
+		data, err = c.encrypt(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(c.path, data)
+}
+
+// Clear removes the cache file, e.g. after HAAPI rejected the cached token.
+func (c *TokenCache) Clear() error {
+	err := os.Remove(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (c *TokenCache) encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := c.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (c *TokenCache) decrypt(ciphertext []byte) ([]byte, error) {
+	gcm, err := c.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("token cache is too short to decrypt")
+	}
+
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+func (c *TokenCache) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeFileAtomic writes data to a temporary file next to path with 0600
+// permissions and renames it into place.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestTokenCacheRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token_cache")
+	if err != nil {
+		t.Fatal("Error creating temp dir.", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache, err := NewTokenCache(path.Join(dir, "token"), "")
+	if err != nil {
+		t.Fatal("Error creating token cache.", err)
+	}
+
+	session, err := cache.Load()
+	if err != nil || session != nil {
+		t.Fatal("Expected no session from a missing cache file.", session, err)
+	}
+
+	if err := cache.Store(&Session{UserID: "12345", Token: "deadbeef"}); err != nil {
+		t.Fatal("Error storing session.", err)
+	}
+
+	info, err := os.Stat(path.Join(dir, "token"))
+	if err != nil {
+		t.Fatal("Error reading token cache file.", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected 0600 permissions but found %v", info.Mode().Perm())
+	}
+
+	session, err = cache.Load()
+	if err != nil {
+		t.Fatal("Error loading session.", err)
+	}
+	if session.UserID != "12345" || session.Token != "deadbeef" || session.Status != "0" {
+		t.Fatal("Loaded session did not match stored session.", session)
+	}
+
+	if err := cache.Clear(); err != nil {
+		t.Fatal("Error clearing token cache.", err)
+	}
+	if session, _ := cache.Load(); session != nil {
+		t.Fatal("Expected no session after clearing the cache.", session)
+	}
+}
+
+func TestTokenCacheEncrypted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token_cache")
+	if err != nil {
+		t.Fatal("Error creating temp dir.", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyFile := path.Join(dir, "key")
+	if err := ioutil.WriteFile(keyFile, []byte("correct horse battery staple\n"), 0600); err != nil {
+		t.Fatal("Error writing key file.", err)
+	}
+	otherKeyFile := path.Join(dir, "other_key")
+	if err := ioutil.WriteFile(otherKeyFile, []byte("something else"), 0600); err != nil {
+		t.Fatal("Error writing key file.", err)
+	}
+
+	cache, err := NewTokenCache(path.Join(dir, "token"), keyFile)
+	if err != nil {
+		t.Fatal("Error creating token cache.", err)
+	}
+
+	if err := cache.Store(&Session{UserID: "12345", Token: "deadbeef"}); err != nil {
+		t.Fatal("Error storing session.", err)
+	}
+
+	data, err := ioutil.ReadFile(path.Join(dir, "token"))
+	if err != nil {
+		t.Fatal("Error reading token cache file.", err)
+	}
+	if strings.Contains(string(data), "deadbeef") {
+		t.Fatal("Token was stored in plain text.")
+	}
+
+	session, err := cache.Load()
+	if err != nil {
+		t.Fatal("Error loading session.", err)
+	}
+	if session.Token != "deadbeef" {
+		t.Fatal("Loaded session did not match stored session.", session)
+	}
+
+	otherCache, err := NewTokenCache(path.Join(dir, "token"), otherKeyFile)
+	if err != nil {
+		t.Fatal("Error creating token cache.", err)
+	}
+	if _, err := otherCache.Load(); err == nil {
+		t.Fatal("Expected an error decrypting with the wrong key.")
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const minutesPerDay = 24 * 60
+
+var (
+	pumpFlow = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pump", "flow_gpm"),
+		"Estimated flow rate of a pump in gallons per minute from its speed and configured flow curve.", []string{"msp_system_id", "system_id", "name"}, nil)
+	bodyOfWaterSize = prometheus.NewDesc(prometheus.BuildFQName(namespace, "body_of_water", "size_gallons"),
+		"Configured size of the body of water in gallons.", []string{"msp_system_id", "system_id", "name"}, nil)
+	bodyOfWaterFlow = prometheus.NewDesc(prometheus.BuildFQName(namespace, "body_of_water", "filtration_flow_gpm"),
+		"Estimated flow rate through the filters of the body of water in gallons per minute.", []string{"msp_system_id", "system_id", "name"}, nil)
+	bodyOfWaterTurnovers = prometheus.NewDesc(prometheus.BuildFQName(namespace, "body_of_water", "turnovers_per_day"),
+		"Estimated number of times the body of water is filtered per day at the current flow rate.", []string{"msp_system_id", "system_id", "name"}, nil)
+	bodyOfWaterFiltered = prometheus.NewDesc(prometheus.BuildFQName(namespace, "body_of_water", "filtered_gallons_total"),
+		"Estimated volume of water filtered since the exporter started.", []string{"msp_system_id", "system_id", "name"}, nil)
+)
+
+// hasFlow reports whether the flow rate of the pump is configured.
+func (p PumpConfig) hasFlow() bool {
+	return len(p.FlowCurve) > 0 || p.RatedGPM > 0
+}
+
+// gpm estimates the flow rate of the pump at the given speed. It prefers the
+// flow curve and falls back to scaling rated_gpm linearly with the speed.
+func (p PumpConfig) gpm(pump MspPump, percent float64) (float64, bool) {
+	if percent <= 0 {
+		return 0, true
+	}
+
+	rpm, haveRPM := pump.rpm(percent)
+
+	if len(p.FlowCurve) > 0 {
+		if !haveRPM {
+			return 0, false
+		}
+		curve := make([]curvePoint, len(p.FlowCurve))
+		for i, point := range p.FlowCurve {
+			curve[i] = curvePoint{rpm: point.RPM, value: point.GPM}
+		}
+		return interpolate(curve, rpm), true
+	}
+
+	ratedRPM := p.RatedRPM
+	if ratedRPM <= 0 {
+		ratedRPM = pump.MaxPumpRPM
+	}
+	if haveRPM && ratedRPM > 0 {
+		return p.RatedGPM * rpm / ratedRPM, true
+	}
+
+	// Without RPM limits, treat the rated flow as the flow at 100%.
+	return p.RatedGPM * percent / 100, true
+}
+
+// buildTurnoverMetrics estimates pump flow rates and the resulting filtration
+// of each body of water.
+func (e *Exporter) buildTurnoverMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	config, ok := e.mspConfigs[mspSystemId]
+	if !ok {
+		return
+	}
+	now := e.now()
+
+	// Flow rate of each configured pump by systemId.
+	flows := map[string]float64{}
+	pumps := config.pumps()
+	for _, telemetry := range pumpSpeedTelemetry {
+		for _, item := range telemetryDataResponse.itemsNamed(telemetry.element) {
+			pumpConfig, ok := e.config.pump(mspSystemId, item.systemId)
+			if !ok || !pumpConfig.hasFlow() {
+				continue
+			}
+
+			speed, err := strconv.ParseFloat(item.attributes[telemetry.speedAttribute], 64)
+			if err != nil {
+				continue
+			}
+
+			pump := pumps[item.systemId]
+			gpm, ok := pumpConfig.gpm(pump, speed)
+			if !ok {
+				continue
+			}
+
+			flows[item.systemId] = gpm
+			ch <- prometheus.MustNewConstMetric(pumpFlow, prometheus.GaugeValue, gpm, mspSystemId, item.systemId, pump.Name)
+		}
+	}
+
+	for _, bow := range config.Backyard.BodiesOfWater {
+		if bow.SizeInGallons > 0 {
+			ch <- prometheus.MustNewConstMetric(bodyOfWaterSize, prometheus.GaugeValue, bow.SizeInGallons, mspSystemId, bow.SystemID, bow.Name)
+		}
+
+		// Only water pumped through a filter counts towards turnover.
+		gpm, known := 0.0, false
+		for _, filter := range bow.Filters {
+			if flow, ok := flows[filter.SystemID]; ok {
+				gpm += flow
+				known = true
+			}
+		}
+		if !known {
+			continue
+		}
+
+		key := mspSystemId + "/" + bow.SystemID
+		filtered, ok := e.filteredGallons[key]
+		if !ok {
+			filtered = &integrator{}
+			e.filteredGallons[key] = filtered
+		}
+		gallons := filtered.add(now, gpm/60)
+
+		ch <- prometheus.MustNewConstMetric(bodyOfWaterFlow, prometheus.GaugeValue, gpm, mspSystemId, bow.SystemID, bow.Name)
+		ch <- prometheus.MustNewConstMetric(bodyOfWaterFiltered, prometheus.CounterValue, gallons, mspSystemId, bow.SystemID, bow.Name)
+
+		if bow.SizeInGallons > 0 {
+			ch <- prometheus.MustNewConstMetric(bodyOfWaterTurnovers, prometheus.GaugeValue, gpm*minutesPerDay/bow.SizeInGallons, mspSystemId, bow.SystemID, bow.Name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPumpGPM(t *testing.T) {
+	pump := MspPump{MinPumpSpeed: 18, MaxPumpSpeed: 100, MinPumpRPM: 600, MaxPumpRPM: 3450}
+	curve := PumpConfig{FlowCurve: []FlowPoint{{RPM: 600, GPM: 20}, {RPM: 3450, GPM: 80}}}
+	rated := PumpConfig{RatedGPM: 80}
+
+	tests := []struct {
+		name    string
+		config  PumpConfig
+		pump    MspPump
+		percent float64
+		gpm     float64
+	}{
+		{"off", curve, pump, 0, 0},
+		{"curve maximum", curve, pump, 100, 80},
+		{"curve interpolated", curve, pump, 59, 20 + (2025.0-600)*60/2850},
+		{"affinity", rated, pump, 59, 80 * 2025.0 / 3450},
+		{"affinity without rpm", rated, MspPump{}, 50, 40},
+	}
+
+	for _, test := range tests {
+		gpm, ok := test.config.gpm(test.pump, test.percent)
+		if !ok || math.Abs(gpm-test.gpm) > 0.001 {
+			t.Fatalf("%v: expected %v gpm but found %v", test.name, test.gpm, gpm)
+		}
+	}
+}
+
+func TestTurnoverMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	telemetryData, err := parseTelemetryDataResponse(string(readFixture(t, "get_telemetry_data_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing telemetry data response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	now := time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC)
+	exporter.now = func() time.Time { return now }
+	exporter.mspConfigs["54321"] = config
+	exporter.config = &Config{Pumps: []PumpConfig{{MspSystemID: "54321", SystemID: "2", RatedGPM: 69}}}
+
+	ch := make(chan prometheus.Metric, 20)
+	exporter.buildTurnoverMetrics(ch, "54321", *telemetryData)
+	collectValues(t, ch)
+
+	now = now.Add(time.Minute)
+	ch = make(chan prometheus.Metric, 20)
+	exporter.buildTurnoverMetrics(ch, "54321", *telemetryData)
+	values := collectValues(t, ch)
+
+	// The filter pump runs at 71%, about 2442 RPM, pumping about 48.8 GPM.
+	rpm := 600 + (71.0-18)*(3450-600)/(100-18)
+	gpm := 69 * rpm / 3450
+
+	if flow := valueOf(t, values, "omnilogic_pump_flow_gpm", "system_id=2"); math.Abs(flow-gpm) > 0.001 {
+		t.Fatalf("Expected pump flow of %v but found %v", gpm, flow)
+	}
+
+	if flow := valueOf(t, values, "omnilogic_body_of_water_filtration_flow_gpm", "system_id=1", "name=Pool"); math.Abs(flow-gpm) > 0.001 {
+		t.Fatalf("Expected filtration flow of %v but found %v", gpm, flow)
+	}
+
+	if size := valueOf(t, values, "omnilogic_body_of_water_size_gallons", "system_id=1"); size != 20000 {
+		t.Fatalf("Expected pool of 20000 gallons but found %v", size)
+	}
+
+	if turnovers := valueOf(t, values, "omnilogic_body_of_water_turnovers_per_day", "system_id=1"); math.Abs(turnovers-gpm*1440/20000) > 0.001 {
+		t.Fatalf("Expected %v turnovers per day but found %v", gpm*1440/20000, turnovers)
+	}
+
+	if gallons := valueOf(t, values, "omnilogic_body_of_water_filtered_gallons_total", "system_id=1"); math.Abs(gallons-gpm) > 0.001 {
+		t.Fatalf("Expected %v gallons filtered in a minute but found %v", gpm, gallons)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	waterTemperatureDegrees = prometheus.NewDesc(prometheus.BuildFQName(namespace, "body_of_water", "temperature_degrees"),
+		"Water temperature, only reported while water flows past the sensor unless the last valid reading is held.", []string{"msp_system_id", "system_id", "name"}, nil)
+	waterTemperatureValid = prometheus.NewDesc(prometheus.BuildFQName(namespace, "body_of_water", "temperature_valid"),
+		"Whether the current water temperature reading is valid, i.e. water is flowing past the sensor.", []string{"msp_system_id", "system_id", "name"}, nil)
+	waterTemperatureLastValid = prometheus.NewDesc(prometheus.BuildFQName(namespace, "body_of_water", "temperature_last_valid_timestamp_seconds"),
+		"When the water temperature was last valid, as seconds since the epoch.", []string{"msp_system_id", "system_id", "name"}, nil)
+)
+
+// waterTemperatureState remembers the last valid water temperature reading of
+// a body of water.
+type waterTemperatureState struct {
+	time        time.Time
+	temperature float64
+}
+
+// waterTemperature returns the water temperature of a BodyOfWater telemetry
+// item and whether it is valid. OmniLogic only measures the water temperature
+// correctly while water flows past the sensor, so readings are stale when the
+// body of water reports no flow, when all of its filter pumps are off, or when
+// the controller reports a negative placeholder such as -1.
+func (e *Exporter) waterTemperature(mspSystemId string, telemetryDataResponse Status, item TelemetryDataItem) (float64, bool) {
+	temperature, err := strconv.ParseFloat(item.attributes["water_temp"], 64)
+	if err != nil || temperature < 0 {
+		return 0, false
+	}
+
+	if flow, ok := item.attributes["flow"]; ok && flow == "0" {
+		return 0, false
+	}
+
+	if config, ok := e.mspConfigs[mspSystemId]; ok {
+		filters := map[string]bool{}
+		for _, bow := range config.Backyard.BodiesOfWater {
+			if bow.SystemID == item.systemId {
+				for _, filter := range bow.Filters {
+					filters[filter.SystemID] = true
+				}
+			}
+		}
+
+		if len(filters) > 0 {
+			running := false
+			for _, filter := range telemetryDataResponse.itemsNamed("filter") {
+				if filters[filter.systemId] && filter.attributes["filter_state"] != "0" {
+					running = true
+				}
+			}
+			if !running {
+				return 0, false
+			}
+		}
+	}
+
+	return temperature, true
+}
+
+// staleWaterTemperature returns a filter for buildMetrics that leaves out the
+// water_temp attribute of bodies of water whose reading is not valid.
+func (e *Exporter) staleWaterTemperature(mspSystemId string, telemetryDataResponse Status) func(item TelemetryDataItem, attribute string) bool {
+	return func(item TelemetryDataItem, attribute string) bool {
+		if item.name != "body_of_water" || attribute != "water_temp" {
+			return false
+		}
+		_, valid := e.waterTemperature(mspSystemId, telemetryDataResponse, item)
+		return !valid
+	}
+}
+
+// buildWaterTemperatureMetrics exports water temperatures gated on flow,
+// optionally holding the last valid reading while the reading is stale.
+func (e *Exporter) buildWaterTemperatureMetrics(ch chan<- prometheus.Metric, mspSystemId string, telemetryDataResponse Status) {
+	names := map[string]string{}
+	if config, ok := e.mspConfigs[mspSystemId]; ok {
+		names = config.equipmentNames()
+	}
+	now := e.now()
+
+	for _, item := range telemetryDataResponse.itemsNamed("body_of_water") {
+		name := names[item.systemId]
+		key := mspSystemId + "/" + item.systemId
+
+		temperature, valid := e.waterTemperature(mspSystemId, telemetryDataResponse, item)
+		if valid {
+			e.waterTemperatures[key] = &waterTemperatureState{time: now, temperature: temperature}
+		}
+
+		validValue := 0.0
+		if valid {
+			validValue = 1
+		}
+		ch <- prometheus.MustNewConstMetric(waterTemperatureValid, prometheus.GaugeValue, validValue, mspSystemId, item.systemId, name)
+
+		last, ok := e.waterTemperatures[key]
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(waterTemperatureLastValid, prometheus.GaugeValue, float64(last.time.Unix()), mspSystemId, item.systemId, name)
+
+		if valid || e.holdWaterTemperature {
+			ch <- prometheus.MustNewConstMetric(waterTemperatureDegrees, prometheus.GaugeValue, last.temperature, mspSystemId, item.systemId, name)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWaterTemperatureMetrics(t *testing.T) {
+	config, err := parseMspConfigFileResponse(string(readFixture(t, "get_msp_config_file_response.xml")))
+
+	if err != nil {
+		t.Fatal("Error parsing MSP config file response.", err)
+	}
+
+	exporter, err := NewExporter("https://example.org", "poolgal@example.org", "MyPassword", 1*time.Second, log.NewNopLogger())
+
+	if err != nil {
+		t.Fatal("Error creating Exporter.", err)
+	}
+
+	start := time.Date(2022, 4, 4, 12, 0, 0, 0, time.UTC)
+	now := start
+	exporter.now = func() time.Time { return now }
+	exporter.mspConfigs["54321"] = config
+
+	poll := func(telemetry string) map[string]float64 {
+		telemetryData, err := parseTelemetryDataResponse(`<STATUS version="1.0">` + telemetry + `</STATUS>`)
+		if err != nil {
+			t.Fatal("Error parsing telemetry data response.", err)
+		}
+		ch := make(chan prometheus.Metric, 20)
+		buildMetrics(ch, "54321", *telemetryData, exporter.staleWaterTemperature("54321", *telemetryData))
+		exporter.buildWaterTemperatureMetrics(ch, "54321", *telemetryData)
+		return collectValues(t, ch)
+	}
+
+	hasTemperature := func(values map[string]float64) bool {
+		for key := range values {
+			if containsAll(key, []string{"\"omnilogic_body_of_water_temperature_degrees\""}) ||
+				containsAll(key, []string{"\"omnilogic_body_of_water_water_temp\""}) {
+				return true
+			}
+		}
+		return false
+	}
+
+	values := poll(`<BodyOfWater systemId="1" flow="1" waterTemp="74" /><Filter systemId="2" filterState="1" />`)
+	if valueOf(t, values, "omnilogic_body_of_water_temperature_valid", "system_id=1", "name=Pool") != 1 {
+		t.Fatal("Expected a valid water temperature while the filter runs.")
+	}
+	if valueOf(t, values, "omnilogic_body_of_water_temperature_degrees", "system_id=1") != 74 ||
+		valueOf(t, values, "omnilogic_body_of_water_water_temp", "system_id=1") != 74 {
+		t.Fatal("Expected a water temperature of 74.")
+	}
+
+	stale := []string{
+		`<BodyOfWater systemId="1" flow="1" waterTemp="60" /><Filter systemId="2" filterState="0" />`,
+		`<BodyOfWater systemId="1" flow="0" waterTemp="60" /><Filter systemId="2" filterState="1" />`,
+		`<BodyOfWater systemId="1" flow="1" waterTemp="-1" /><Filter systemId="2" filterState="1" />`,
+	}
+
+	for _, telemetry := range stale {
+		now = now.Add(time.Minute)
+		values = poll(telemetry)
+		if valueOf(t, values, "omnilogic_body_of_water_temperature_valid", "system_id=1") != 0 {
+			t.Fatalf("Expected a stale water temperature for %v", telemetry)
+		}
+		if valueOf(t, values, "omnilogic_body_of_water_temperature_last_valid_timestamp_seconds", "system_id=1") != float64(start.Unix()) {
+			t.Fatalf("Expected the last valid timestamp to be kept for %v", telemetry)
+		}
+		if hasTemperature(values) {
+			t.Fatalf("Expected no water temperature for %v", telemetry)
+		}
+	}
+
+	exporter.holdWaterTemperature = true
+	values = poll(stale[0])
+	if valueOf(t, values, "omnilogic_body_of_water_temperature_degrees", "system_id=1") != 74 {
+		t.Fatal("Expected the last valid water temperature to be held.")
+	}
+}